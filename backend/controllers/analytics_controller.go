@@ -0,0 +1,361 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"pipeline-backend/money"
+	"pipeline-backend/pagination"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type AnalyticsController struct {
+	DB *gorm.DB
+}
+
+func NewAnalyticsController(db *gorm.DB) *AnalyticsController {
+	return &AnalyticsController{DB: db}
+}
+
+// Metric value kinds, deciding how a column is scanned and encoded
+const (
+	metricCount   = "count"
+	metricAmount  = "amount"
+	metricPercent = "percent"
+)
+
+// analyticsMetric - A whitelisted aggregate over the filtered DI319 rows (alias d), and the same
+// aggregate over di319_summary ("" when it cannot be derived from the summary, e.g. distinct counts)
+type analyticsMetric struct {
+	SQL     string
+	Summary string
+	Kind    string
+}
+
+var di319AnalyticsMetrics = map[string]analyticsMetric{
+	"count":            {"COUNT(*)", "COALESCE(SUM(d.row_count), 0)", metricCount},
+	"accounts":         {"COUNT(DISTINCT d.norek)", "", metricCount},
+	"customers":        {"COUNT(DISTINCT d.cif)", "", metricCount},
+	"sum_balance":      {"COALESCE(SUM(d.balance), 0)", "COALESCE(SUM(d.sum_balance), 0)", metricAmount},
+	"avg_balance":      {"ROUND(AVG(d.balance), 2)", "ROUND(SUM(d.sum_balance) / NULLIF(SUM(d.row_count), 0), 2)", metricAmount},
+	"sum_aval_balance": {"COALESCE(SUM(d.aval_balance), 0)", "COALESCE(SUM(d.sum_aval_balance), 0)", metricAmount},
+	"sum_avg_balance":  {"COALESCE(SUM(d.avg_balance), 0)", "COALESCE(SUM(d.sum_avg_balance), 0)", metricAmount},
+	"drop_count":       {"COALESCE(SUM(d.qualifying_rule <> ''), 0)", "COALESCE(SUM(d.drop_count), 0)", metricCount},
+	"sum_drop":         {"COALESCE(SUM(CASE WHEN d.drop_amount > 0 THEN d.drop_amount END), 0)", "COALESCE(SUM(d.sum_drop), 0)", metricAmount},
+	"avg_drop_pct":     {"ROUND(AVG(d.drop_pct), 2)", "ROUND(SUM(d.sum_drop_pct) / NULLIF(SUM(d.drop_pct_count), 0), 2)", metricPercent},
+	"max_drop_pct":     {"MAX(d.drop_pct)", "MAX(d.max_drop_pct)", metricPercent},
+}
+
+var di319AnalyticsDefaultMetrics = []string{"count", "sum_balance", "sum_drop", "avg_drop_pct"}
+
+// analyticsSources - Tables the pivot can run over
+var analyticsSources = map[string]string{
+	"di319":    "di319",          // drop candidates
+	"snapshot": "di319_snapshot", // every account of every imported periode
+}
+
+// analyticsNode - One level of the nested output: a dimension value with either children or metrics
+type analyticsNode struct {
+	Dimension string                 `json:"dimension"`
+	Value     string                 `json:"value"`
+	Label     string                 `json:"label,omitempty"`
+	Metrics   map[string]interface{} `json:"metrics,omitempty"`
+	Children  []*analyticsNode       `json:"children,omitempty"`
+}
+
+// DI319 - Pivot DI319 by any whitelisted dimensions and metrics
+// (GET /api/analytics/di319?group_by=region,type&metrics=count,sum_balance,sum_drop,avg_drop_pct).
+// Accepts the DI319 list filters plus:
+//   - group_by: branch, main_branch, region, type, pn_pengelola, periode (none for a grand total)
+//   - metrics: count, accounts, customers, sum_balance, avg_balance, sum_aval_balance, sum_avg_balance,
+//     drop_count, sum_drop, avg_drop_pct, max_drop_pct
+//   - format=table (flat rows, default) | nested (a tree following group_by)
+//   - source=di319 (default) | snapshot
+//   - sort=<metric>&order=asc|desc (default: by the dimensions), limit (default and max 1000 rows)
+//
+// di319 queries read di319_summary when the filters and metrics allow it (not with ?summary=false).
+func (c *AnalyticsController) DI319(ctx *fiber.Ctx) error {
+	filter, err := parseDI319Filters(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := applyDI319DataScope(c.DB, ctx, filter); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load data scope",
+		})
+	}
+
+	dimensions := pagination.SplitList(ctx.Query("group_by"))
+	if dimensions == nil {
+		dimensions = []string{}
+	}
+	seen := make(map[string]bool)
+	for _, name := range dimensions {
+		if _, ok := di319StatsDimensions[name]; !ok {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("invalid group_by: %s", name),
+			})
+		}
+		if seen[name] {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("duplicate group_by: %s", name),
+			})
+		}
+		seen[name] = true
+	}
+
+	metrics := pagination.SplitList(ctx.Query("metrics"))
+	if len(metrics) == 0 {
+		metrics = di319AnalyticsDefaultMetrics
+	}
+	for _, name := range metrics {
+		if _, ok := di319AnalyticsMetrics[name]; !ok {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("invalid metric: %s", name),
+			})
+		}
+	}
+
+	format := ctx.Query("format", "table")
+	if format != "table" && format != "nested" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid format: %s", format),
+		})
+	}
+
+	source := ctx.Query("source", "di319")
+	table, ok := analyticsSources[source]
+	if !ok {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid source: %s", source),
+		})
+	}
+
+	order := strings.ToLower(ctx.Query("order", "desc"))
+	if order != "asc" && order != "desc" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid order: %s", order),
+		})
+	}
+	sortMetric := ctx.Query("sort")
+	if sortMetric != "" && !containsString(metrics, sortMetric) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid sort: %s (must be one of the requested metrics)", sortMetric),
+		})
+	}
+
+	limit := pagination.MaxPageSize
+	if raw := ctx.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("invalid limit: %s", raw),
+			})
+		}
+		limit = pagination.ClampLimit(limit)
+	}
+
+	fromSummary := source == "di319" && useDI319Summary(ctx)
+	for _, name := range metrics {
+		if di319AnalyticsMetrics[name].Summary == "" {
+			fromSummary = false
+		}
+	}
+	if fromSummary {
+		table = "di319_summary"
+	}
+
+	// Build the query: dimension columns (d0, d0_label, ...) then metric columns (m0, ...)
+	var selects, keys, joins, orders []string
+	for i, name := range dimensions {
+		dim := di319StatsDimensions[name]
+		selects = append(selects, fmt.Sprintf("%s AS d%d", dim.Key, i))
+		if dim.Label != "" {
+			selects = append(selects, fmt.Sprintf("%s AS d%d_label", dim.Label, i))
+		}
+		keys = append(keys, dim.Key)
+		if dim.Joins != "" && !containsString(joins, dim.Joins) {
+			joins = append(joins, dim.Joins)
+		}
+		direction := "ASC"
+		if dim.Desc {
+			direction = "DESC"
+		}
+		orders = append(orders, fmt.Sprintf("d%d %s", i, direction))
+	}
+	for i, name := range metrics {
+		metric := di319AnalyticsMetrics[name].SQL
+		if fromSummary {
+			metric = di319AnalyticsMetrics[name].Summary
+		}
+		selects = append(selects, fmt.Sprintf("%s AS m%d", metric, i))
+		if name == sortMetric {
+			orders = append([]string{fmt.Sprintf("m%d %s", i, strings.ToUpper(order))}, orders...)
+		}
+	}
+
+	query := "SELECT " + strings.Join(selects, ", ") +
+		" FROM (SELECT * FROM " + table + " WHERE " + filter.SQL() + ") d" + strings.Join(joins, "")
+	if len(keys) > 0 {
+		query += " GROUP BY " + strings.Join(keys, ", ")
+	}
+	if len(orders) > 0 {
+		query += " ORDER BY " + strings.Join(orders, ", ")
+	}
+	// One extra row tells whether the result was truncated
+	query += " LIMIT ?"
+	args := append(append([]interface{}{}, filter.Args...), limit+1)
+
+	rows, err := c.DB.Raw(query, args...).Rows()
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	defer rows.Close()
+
+	result := make([]fiber.Map, 0)
+	for rows.Next() {
+		row, err := scanAnalyticsRow(rows, dimensions, metrics)
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	truncated := len(result) > limit
+	if truncated {
+		result = result[:limit]
+	}
+
+	response := fiber.Map{
+		"source":       source,
+		"from_summary": fromSummary,
+		"group_by":     dimensions,
+		"metrics":      metrics,
+		"rows":         len(result),
+		"truncated":    truncated,
+	}
+	if format == "nested" {
+		response["data"] = nestAnalyticsRows(result, dimensions, metrics)
+	} else {
+		response["data"] = result
+	}
+	return ctx.JSON(response)
+}
+
+// scanAnalyticsRow - Read one result row into {dimension: value, dimension_label: label, metric: value}
+func scanAnalyticsRow(rows *sql.Rows, dimensions, metrics []string) (fiber.Map, error) {
+	var targets []interface{}
+	dimValues := make([]sql.NullString, len(dimensions))
+	labelValues := make([]sql.NullString, len(dimensions))
+	for i, name := range dimensions {
+		targets = append(targets, &dimValues[i])
+		if di319StatsDimensions[name].Label != "" {
+			targets = append(targets, &labelValues[i])
+		}
+	}
+	metricValues := make([]interface{}, len(metrics))
+	for i, name := range metrics {
+		switch di319AnalyticsMetrics[name].Kind {
+		case metricCount:
+			metricValues[i] = new(int64)
+		case metricAmount:
+			metricValues[i] = new(money.Amount)
+		default:
+			metricValues[i] = new(sql.NullFloat64)
+		}
+		targets = append(targets, metricValues[i])
+	}
+
+	if err := rows.Scan(targets...); err != nil {
+		return nil, err
+	}
+
+	row := fiber.Map{}
+	for i, name := range dimensions {
+		row[name] = dimValues[i].String
+		if di319StatsDimensions[name].Label != "" {
+			row[name+"_label"] = labelValues[i].String
+		}
+	}
+	for i, name := range metrics {
+		switch v := metricValues[i].(type) {
+		case *int64:
+			row[name] = *v
+		case *money.Amount:
+			row[name] = *v
+		case *sql.NullFloat64:
+			if v.Valid {
+				row[name] = v.Float64
+			} else {
+				row[name] = nil
+			}
+		}
+	}
+	return row, nil
+}
+
+// nestAnalyticsRows - Turn flat rows (ordered by the dimensions) into a tree following group_by.
+// Leaves carry the metrics; with no dimensions the single total row is returned as is.
+func nestAnalyticsRows(rows []fiber.Map, dimensions, metrics []string) interface{} {
+	if len(dimensions) == 0 {
+		if len(rows) == 0 {
+			return fiber.Map{}
+		}
+		return rows[0]
+	}
+
+	root := &analyticsNode{}
+	index := make(map[*analyticsNode]map[string]*analyticsNode)
+	for _, row := range rows {
+		parent := root
+		for level, name := range dimensions {
+			value := row[name].(string)
+			if index[parent] == nil {
+				index[parent] = make(map[string]*analyticsNode)
+			}
+			node, ok := index[parent][value]
+			if !ok {
+				node = &analyticsNode{Dimension: name, Value: value}
+				if label, ok := row[name+"_label"].(string); ok {
+					node.Label = label
+				}
+				index[parent][value] = node
+				parent.Children = append(parent.Children, node)
+			}
+			if level == len(dimensions)-1 {
+				node.Metrics = make(map[string]interface{}, len(metrics))
+				for _, metric := range metrics {
+					node.Metrics[metric] = row[metric]
+				}
+			}
+			parent = node
+		}
+	}
+	if root.Children == nil {
+		return []*analyticsNode{}
+	}
+	return root.Children
+}
+
+// containsString - Whether list holds s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
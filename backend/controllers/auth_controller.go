@@ -2,6 +2,8 @@ package controllers
 
 import (
 	"pipeline-backend/models"
+	"pipeline-backend/pagination"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -180,15 +182,54 @@ func (c *AuthController) GetProfile(ctx *fiber.Ctx) error {
 
 	return ctx.JSON(fiber.Map{
 		"user": fiber.Map{
-			"id":        user.ID,
-			"username":  user.Username,
-			"full_name": user.FullName,
-			"email":     user.Email,
-			"role":      user.Role,
+			"id":         user.ID,
+			"username":   user.Username,
+			"full_name":  user.FullName,
+			"email":      user.Email,
+			"role":       user.Role,
+			"data_scope": user.DataScope,
 		},
 	})
 }
 
+// SetDataScope - Limit which branches a user sees in exports (admin only).
+// Body: {"data_scope": "10272,10168"} - branch or main_branch codes, empty for all data.
+func (c *AuthController) SetDataScope(ctx *fiber.Ctx) error {
+	var req struct {
+		DataScope string `json:"data_scope"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var user models.User
+	if err := c.DB.First(&user, ctx.Params("id")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	scope := strings.Join(pagination.SplitList(req.DataScope), ",")
+	if len(scope) > 500 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "data_scope must be at most 500 characters",
+		})
+	}
+	if err := c.DB.Model(&user).Update("data_scope", scope).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update data scope",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message":    "Data scope updated successfully",
+		"id":         user.ID,
+		"data_scope": scope,
+	})
+}
+
 // ChangePassword - Change user password
 func (c *AuthController) ChangePassword(ctx *fiber.Ctx) error {
 	userID := ctx.Locals("user_id")
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"pipeline-backend/models"
+	"pipeline-backend/money"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type CustomerController struct {
+	DB *gorm.DB
+}
+
+func NewCustomerController(db *gorm.DB) *CustomerController {
+	return &CustomerController{DB: db}
+}
+
+// di319TrendPoint - One account in one periode
+type di319TrendPoint struct {
+	Periode        time.Time     `json:"periode"`
+	Branch         string        `json:"branch"`
+	PNPengelola    string        `json:"pn_pengelola"`
+	Balance        money.Amount  `json:"balance"`
+	AvalBalance    money.Amount  `json:"aval_balance"`
+	AvgBalance     *money.Amount `json:"avg_balance"`
+	DropAmount     *money.Amount `json:"drop_amount"`
+	DropPct        *float64      `json:"drop_pct"`
+	QualifyingRule string        `json:"qualifying_rule"`
+}
+
+func newDI319TrendPoint(row models.DI319) di319TrendPoint {
+	return di319TrendPoint{
+		Periode:        row.Periode,
+		Branch:         row.Branch,
+		PNPengelola:    row.PNPengelola,
+		Balance:        row.Balance,
+		AvalBalance:    row.AvalBalance,
+		AvgBalance:     row.AvgBalance,
+		DropAmount:     row.DropAmount,
+		DropPct:        row.DropPct,
+		QualifyingRule: row.QualifyingRule,
+	}
+}
+
+// customerAccount - One account (norek) of the customer with its balance trend, oldest first
+type customerAccount struct {
+	NoRek    string            `json:"norek"`
+	Type     string            `json:"type"`
+	Branch   string            `json:"branch"`
+	OpenDate time.Time         `json:"open_date"`
+	Latest   di319TrendPoint   `json:"latest"`
+	Trend    []di319TrendPoint `json:"trend"`
+}
+
+// GetByCIF - Customer 360: every account of a CIF across all imported periodes,
+// the officer and uker it belongs to and its pipeline (drop candidate) records
+func (c *CustomerController) GetByCIF(ctx *fiber.Ctx) error {
+	cif := ctx.Params("cif")
+	scope, err := di319ScopeFilter(c.DB, ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load data scope",
+		})
+	}
+
+	// The full snapshot has every account, dropped or not; periodes imported without it only have di319 candidates
+	var snapshotRows []models.DI319Snapshot
+	if c.DB.Migrator().HasTable(&models.DI319Snapshot{}) {
+		if err := scope.Apply(c.DB.Where("cif = ?", cif)).Find(&snapshotRows).Error; err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	var pipelines []models.DI319
+	if err := scope.Apply(c.DB.Where("cif = ?", cif)).Order("periode DESC, id DESC").Find(&pipelines).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	rows, source := mergeDI319Sources(snapshotRows, pipelines)
+
+	if len(rows) == 0 {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Customer not found",
+		})
+	}
+
+	// Group into accounts; rows are ordered by norek then periode
+	var accounts []*customerAccount
+	periodes := make(map[time.Time]bool)
+	var latest models.DI319
+	for _, row := range rows {
+		point := newDI319TrendPoint(row)
+		if len(accounts) == 0 || accounts[len(accounts)-1].NoRek != row.NoRek {
+			accounts = append(accounts, &customerAccount{NoRek: row.NoRek, OpenDate: row.OpenDate})
+		}
+		account := accounts[len(accounts)-1]
+		account.Type, account.Branch, account.Latest = row.Type, row.Branch, point
+		account.Trend = append(account.Trend, point)
+
+		periodes[row.Periode] = true
+		if !row.Periode.Before(latest.Periode) {
+			latest = row
+		}
+	}
+
+	// Totals over the latest periode the customer appears in
+	var totalBalance, totalAvgBalance, totalDrop money.Amount
+	for _, account := range accounts {
+		if !account.Latest.Periode.Equal(latest.Periode) {
+			continue
+		}
+		totalBalance += account.Latest.Balance
+		if account.Latest.AvgBalance != nil {
+			totalAvgBalance += *account.Latest.AvgBalance
+		}
+		if account.Latest.DropAmount != nil && *account.Latest.DropAmount > 0 {
+			totalDrop += *account.Latest.DropAmount
+		}
+	}
+
+	return ctx.JSON(fiber.Map{
+		"cif":          cif,
+		"nama":         latest.Nama,
+		"branch":       latest.Branch,
+		"main_branch":  latest.MainBranch,
+		"pn_pengelola": latest.PNPengelola,
+		"rmft":         c.findOfficer(latest.PNPengelola),
+		"uker":         c.findUker(latest.Branch),
+		"source":       source,
+		"summary": fiber.Map{
+			"accounts":          len(accounts),
+			"periodes":          len(periodes),
+			"latest_periode":    latest.Periode,
+			"total_balance":     totalBalance,
+			"total_avg_balance": totalAvgBalance,
+			"total_drop_amount": totalDrop,
+			"pipeline_records":  len(pipelines),
+		},
+		"accounts":  accounts,
+		"pipelines": pipelines,
+	})
+}
+
+// mergeDI319Sources - One row per account and periode, ordered by norek then periode. A snapshot row wins
+// over the di319 candidate row of the same periode; of duplicate di319 rows the last imported one is kept.
+// The source is "snapshot", "di319" or "mixed".
+func mergeDI319Sources(snapshotRows []models.DI319Snapshot, di319Rows []models.DI319) ([]models.DI319, string) {
+	type key struct {
+		norek   string
+		periode time.Time
+	}
+	type sourced struct {
+		row      models.DI319
+		snapshot bool
+	}
+
+	candidates := make([]models.DI319, len(di319Rows))
+	copy(candidates, di319Rows)
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].ID < candidates[j].ID })
+
+	merged := make(map[key]sourced, len(snapshotRows)+len(candidates))
+	for _, row := range candidates {
+		merged[key{row.NoRek, row.Periode}] = sourced{row: row}
+	}
+	for _, row := range snapshotRows {
+		merged[key{row.NoRek, row.Periode}] = sourced{row: row.DI319, snapshot: true}
+	}
+
+	rows := make([]models.DI319, 0, len(merged))
+	fromSnapshot := 0
+	for _, m := range merged {
+		rows = append(rows, m.row)
+		if m.snapshot {
+			fromSnapshot++
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].NoRek != rows[j].NoRek {
+			return rows[i].NoRek < rows[j].NoRek
+		}
+		return rows[i].Periode.Before(rows[j].Periode)
+	})
+
+	switch fromSnapshot {
+	case 0:
+		return rows, "di319"
+	case len(rows):
+		return rows, "snapshot"
+	default:
+		return rows, "mixed"
+	}
+}
+
+// findOfficer - The RMFT officer for a PN pengelola (DI319 writes "PN123456", RFMT may store "123456")
+func (c *CustomerController) findOfficer(pn string) *models.RFMT {
+	if pn == "" || pn == "UNKNOWN" {
+		return nil
+	}
+	var officer models.RFMT
+	err := c.DB.Preload("UkerRelation").
+		Where("pn IN ?", []string{pn, strings.TrimPrefix(strings.ToUpper(pn), "PN")}).
+		Order("created_at DESC").First(&officer).Error
+	if err != nil {
+		return nil
+	}
+	return &officer
+}
+
+// findUker - The uker for a DI319 branch code
+func (c *CustomerController) findUker(branch string) *models.Uker {
+	var uker models.Uker
+	if err := c.DB.Where("kode_uker = ?", branch).First(&uker).Error; err != nil {
+		return nil
+	}
+	return &uker
+}
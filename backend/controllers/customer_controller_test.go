@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"pipeline-backend/models"
+	"pipeline-backend/money"
+	"testing"
+	"time"
+)
+
+func TestMergeDI319Sources(t *testing.T) {
+	jan := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC)
+	row := func(id uint, norek string, periode time.Time, balance int64) models.DI319 {
+		return models.DI319{ID: id, NoRek: norek, Periode: periode, Balance: money.FromInt(balance)}
+	}
+	snapshot := func(r models.DI319) models.DI319Snapshot {
+		return models.DI319Snapshot{DI319: r}
+	}
+
+	tests := []struct {
+		name       string
+		snapshot   []models.DI319Snapshot
+		di319      []models.DI319
+		wantSource string
+		want       []models.DI319 // norek, periode and balance are compared
+	}{
+		{
+			name:       "di319 only, duplicates keep the last import",
+			di319:      []models.DI319{row(2, "A", jan, 20), row(1, "A", jan, 10)},
+			wantSource: "di319",
+			want:       []models.DI319{row(0, "A", jan, 20)},
+		},
+		{
+			name:       "snapshot only",
+			snapshot:   []models.DI319Snapshot{snapshot(row(0, "A", feb, 5)), snapshot(row(0, "A", jan, 7))},
+			wantSource: "snapshot",
+			want:       []models.DI319{row(0, "A", jan, 7), row(0, "A", feb, 5)},
+		},
+		{
+			name:       "periodes without a snapshot come from di319, snapshot wins per periode",
+			snapshot:   []models.DI319Snapshot{snapshot(row(0, "A", feb, 5))},
+			di319:      []models.DI319{row(1, "A", jan, 10), row(2, "A", feb, 99), row(3, "B", jan, 1)},
+			wantSource: "mixed",
+			want:       []models.DI319{row(0, "A", jan, 10), row(0, "A", feb, 5), row(0, "B", jan, 1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, source := mergeDI319Sources(tt.snapshot, tt.di319)
+			if source != tt.wantSource {
+				t.Errorf("source = %q, want %q", source, tt.wantSource)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d", len(rows), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := rows[i]
+				if got.NoRek != want.NoRek || !got.Periode.Equal(want.Periode) || got.Balance != want.Balance {
+					t.Errorf("row %d = %s %s %s, want %s %s %s", i,
+						got.NoRek, got.Periode.Format("2006-01-02"), got.Balance,
+						want.NoRek, want.Periode.Format("2006-01-02"), want.Balance)
+				}
+			}
+		})
+	}
+}
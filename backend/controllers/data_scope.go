@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"log"
+	"pipeline-backend/models"
+	"pipeline-backend/pagination"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// userDataScope - Branch / main branch codes the current user may see; nil means everything.
+// Admins are never restricted.
+func userDataScope(db *gorm.DB, ctx *fiber.Ctx) ([]string, error) {
+	if ctx.Locals("role") == "admin" {
+		return nil, nil
+	}
+	var user models.User
+	if err := db.Select("id", "data_scope").First(&user, ctx.Locals("user_id")).Error; err != nil {
+		return nil, err
+	}
+	return pagination.SplitList(user.DataScope), nil
+}
+
+// applyDI319DataScope - Restrict a DI319 filter to the user's scope (rows whose branch or main_branch is in it).
+// Every endpoint serving DI319 rows or aggregates applies it, so the scope is access control, not an export option.
+func applyDI319DataScope(db *gorm.DB, ctx *fiber.Ctx, filter *pagination.Filter) error {
+	scope, err := userDataScope(db, ctx)
+	if err != nil || len(scope) == 0 {
+		return err
+	}
+	filter.Add("(branch IN ? OR main_branch IN ?)", scope, scope)
+	return nil
+}
+
+// di319ScopeFilter - The user's data scope on its own, for queries that do not take the list filters
+// (SQL() is "1=1" when the user is not restricted)
+func di319ScopeFilter(db *gorm.DB, ctx *fiber.Ctx) (*pagination.Filter, error) {
+	filter := &pagination.Filter{}
+	return filter, applyDI319DataScope(db, ctx, filter)
+}
+
+// writeAuditLog - Record an action of the current user. Failures are logged, never returned:
+// the audited action has already happened.
+func writeAuditLog(db *gorm.DB, userID uint, username, action, resource, details string, rows int64) {
+	entry := models.AuditLog{
+		UserID:   userID,
+		Username: username,
+		Action:   action,
+		Resource: resource,
+		Details:  details,
+		Rows:     rows,
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		log.Printf("⚠️  Failed to write audit log (%s %s by %s): %v", action, resource, username, err)
+	}
+}
+
+// auditUser - The current user's ID and username, for audit entries
+func auditUser(ctx *fiber.Ctx) (uint, string) {
+	userID, _ := ctx.Locals("user_id").(uint)
+	username, _ := ctx.Locals("username").(string)
+	return userID, strings.TrimSpace(username)
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"pipeline-backend/money"
+	"pipeline-backend/pagination"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// di319CompareDimensions - Columns the comparison can be broken down by
+var di319CompareDimensions = map[string]bool{
+	"branch":       true,
+	"main_branch":  true,
+	"type":         true,
+	"pn_pengelola": true,
+}
+
+var di319CompareFilterFields = []pagination.FilterField{
+	{Param: "branch", Column: "branch", Kind: pagination.FilterIn},
+	{Param: "main_branch", Column: "main_branch", Kind: pagination.FilterIn},
+	{Param: "type", Column: "type", Kind: pagination.FilterIn},
+	{Param: "pn_pengelola", Column: "pn_pengelola", Kind: pagination.FilterIn},
+}
+
+// di319CompareRow - Movement of drop candidates between two periodes for one group (or overall).
+// Recovered and closed need the full snapshot of both periodes; from di319 alone an account that is
+// no longer flagged may have recovered or closed, so those are only reported together as Left.
+type di319CompareRow struct {
+	Branch      string `json:"branch,omitempty"`
+	MainBranch  string `json:"main_branch,omitempty"`
+	Type        string `json:"type,omitempty"`
+	PNPengelola string `json:"pn_pengelola,omitempty"`
+
+	FromCount    int64        `json:"from_count"` // candidates in the from periode
+	FromBalance  money.Amount `json:"from_balance"`
+	ToCount      int64        `json:"to_count"` // candidates in the to periode
+	ToBalance    money.Amount `json:"to_balance"`
+	DeltaCount   int64        `json:"delta_count"`
+	DeltaBalance money.Amount `json:"delta_balance"`
+
+	NewCount         int64         `json:"new_count"` // flagged in to, not in from
+	NewBalance       money.Amount  `json:"new_balance"`
+	StayedCount      int64         `json:"stayed_count"` // flagged in both
+	StayedBalance    money.Amount  `json:"stayed_balance"`
+	RecoveredCount   *int64        `json:"recovered_count"` // still open in to, no longer flagged
+	RecoveredBalance *money.Amount `json:"recovered_balance"`
+	ClosedCount      *int64        `json:"closed_count"` // norek gone from to
+	ClosedBalance    *money.Amount `json:"closed_balance"`
+	LeftCount        int64         `json:"left_count"` // recovered + closed
+	LeftBalance      money.Amount  `json:"left_balance"`
+}
+
+const di319CompareColumns = `COALESCE(SUM(from_balance IS NOT NULL), 0) AS from_count,
+	COALESCE(SUM(from_balance), 0) AS from_balance,
+	COALESCE(SUM(to_balance IS NOT NULL), 0) AS to_count,
+	COALESCE(SUM(to_balance), 0) AS to_balance,
+	COALESCE(SUM(category = 'new'), 0) AS new_count,
+	COALESCE(SUM(CASE WHEN category = 'new' THEN to_balance END), 0) AS new_balance,
+	COALESCE(SUM(category = 'stayed'), 0) AS stayed_count,
+	COALESCE(SUM(CASE WHEN category = 'stayed' THEN to_balance END), 0) AS stayed_balance,
+	COALESCE(SUM(category = 'recovered'), 0) AS recovered_count,
+	COALESCE(SUM(CASE WHEN category = 'recovered' THEN current_balance END), 0) AS recovered_balance,
+	COALESCE(SUM(category = 'closed'), 0) AS closed_count,
+	COALESCE(SUM(CASE WHEN category = 'closed' THEN from_balance END), 0) AS closed_balance,
+	COALESCE(SUM(category IN ('recovered', 'closed', 'left')), 0) AS left_count,
+	COALESCE(SUM(CASE WHEN category IN ('recovered', 'closed', 'left') THEN from_balance END), 0) AS left_balance`
+
+// di319CompareSide - One row per norek of a periode (re-imports may have duplicated rows)
+const di319CompareSide = `(SELECT norek, MAX(branch) AS branch, MAX(main_branch) AS main_branch, MAX(type) AS type,
+	MAX(pn_pengelola) AS pn_pengelola, MAX(balance) AS balance, MAX(qualifying_rule <> '') AS flagged
+	FROM %s WHERE periode = ? GROUP BY norek)`
+
+// GetCompare - Period-over-period movement of drop candidates
+// (GET /api/di319/compare?from=2025-01&to=2025-02):
+//   - from, to: a month (yyyy-mm, its latest imported periode) or an exact periode (yyyy-mm-dd)
+//   - group_by: any of branch, main_branch, type, pn_pengelola (default branch,type,pn_pengelola)
+//   - branch, main_branch, type, pn_pengelola: filters (comma separated)
+//   - source=snapshot|di319 (default snapshot when both periodes were snapshotted)
+//   - limit: groups returned (default and max 1000)
+//
+// Accounts are grouped by their attributes in the to periode, or the from periode once gone.
+func (c *DI319ImportController) GetCompare(ctx *fiber.Ctx) error {
+	groupBy := pagination.SplitList(ctx.Query("group_by", "branch,type,pn_pengelola"))
+	for _, name := range groupBy {
+		if !di319CompareDimensions[name] {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("invalid group_by: %s", name),
+			})
+		}
+	}
+
+	filter, err := pagination.ParseFilters(ctx, di319CompareFilterFields)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := applyDI319DataScope(c.DB, ctx, filter); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load data scope",
+		})
+	}
+
+	limit := pagination.ClampLimit(ctx.QueryInt("limit", pagination.MaxPageSize))
+
+	source := ctx.Query("source")
+	if source != "" && source != "snapshot" && source != "di319" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid source: %s", source),
+		})
+	}
+
+	fromStart, fromEnd, err := parseComparePeriode("from", ctx.Query("from"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	toStart, toEnd, err := parseComparePeriode("to", ctx.Query("to"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Resolve both periodes, preferring the snapshot unless di319 was asked for
+	var fromPeriode, toPeriode *time.Time
+	for _, candidate := range []string{"snapshot", "di319"} {
+		if source != "" && source != candidate {
+			continue
+		}
+		table := analyticsSources[candidate]
+		if fromPeriode, err = c.latestPeriodeBetween(table, fromStart, fromEnd); err == nil {
+			toPeriode, err = c.latestPeriodeBetween(table, toStart, toEnd)
+		}
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if fromPeriode != nil && toPeriode != nil {
+			source = candidate
+			break
+		}
+	}
+	if fromPeriode == nil || toPeriode == nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No imported periode matches from / to",
+		})
+	}
+	if !toPeriode.After(*fromPeriode) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "to must be after from",
+		})
+	}
+
+	// Classify every account flagged in either periode, then aggregate per group
+	table := analyticsSources[source]
+	side := fmt.Sprintf(di319CompareSide, table)
+	left := "'left'"
+	if source == "snapshot" {
+		left = "CASE WHEN b.norek IS NULL THEN 'closed' ELSE 'recovered' END"
+	}
+	fromDay, toDay := fromPeriode.Format("2006-01-02"), toPeriode.Format("2006-01-02")
+	accounts := `SELECT COALESCE(b.branch, a.branch) AS branch, COALESCE(b.main_branch, a.main_branch) AS main_branch,
+			COALESCE(b.type, a.type) AS type, COALESCE(b.pn_pengelola, a.pn_pengelola) AS pn_pengelola,
+			CASE WHEN b.flagged = 1 THEN 'stayed' ELSE ` + left + ` END AS category,
+			a.balance AS from_balance, CASE WHEN b.flagged = 1 THEN b.balance END AS to_balance, b.balance AS current_balance
+		FROM ` + side + ` a LEFT JOIN ` + side + ` b ON b.norek = a.norek
+		WHERE a.flagged = 1
+		UNION ALL
+		SELECT b.branch, b.main_branch, b.type, b.pn_pengelola, 'new', NULL, b.balance, b.balance
+		FROM ` + side + ` b LEFT JOIN ` + side + ` a ON a.norek = b.norek
+		WHERE b.flagged = 1 AND (a.norek IS NULL OR a.flagged = 0)`
+	accountArgs := []interface{}{fromDay, toDay, toDay, fromDay}
+
+	fromSQL := " FROM (" + accounts + ") x WHERE " + filter.SQL()
+	args := append(accountArgs, filter.Args...)
+
+	var totals di319CompareRow
+	if err := c.DB.Raw("SELECT "+di319CompareColumns+fromSQL, args...).Scan(&totals).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	groups := []di319CompareRow{}
+	if len(groupBy) > 0 {
+		keys := strings.Join(groupBy, ", ")
+		query := "SELECT " + keys + ", " + di319CompareColumns + fromSQL + " GROUP BY " + keys + " ORDER BY " + keys + " LIMIT ?"
+		if err := c.DB.Raw(query, append(args, limit)...).Scan(&groups).Error; err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	totals.finish(source)
+	for i := range groups {
+		groups[i].finish(source)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"from":     fromDay,
+		"to":       toDay,
+		"source":   source,
+		"group_by": groupBy,
+		"totals":   totals,
+		"groups":   groups,
+	})
+}
+
+// finish - Fill the deltas; without a snapshot recovered / closed are unknown
+func (r *di319CompareRow) finish(source string) {
+	r.DeltaCount = r.ToCount - r.FromCount
+	r.DeltaBalance = r.ToBalance - r.FromBalance
+	if source != "snapshot" {
+		r.RecoveredCount, r.RecoveredBalance = nil, nil
+		r.ClosedCount, r.ClosedBalance = nil, nil
+	}
+}
+
+// parseComparePeriode - yyyy-mm (the whole month) or yyyy-mm-dd (that day) as an inclusive date range
+func parseComparePeriode(param, raw string) (time.Time, time.Time, error) {
+	if day, err := time.Parse("2006-01-02", raw); err == nil {
+		return day, day, nil
+	}
+	if month, err := time.Parse("2006-01", raw); err == nil {
+		return month, month.AddDate(0, 1, -1), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid %s: %q (expected yyyy-mm or yyyy-mm-dd)", param, raw)
+}
+
+// latestPeriodeBetween - The latest periode of table within [start, end], nil when there is none
+func (c *DI319ImportController) latestPeriodeBetween(table string, start, end time.Time) (*time.Time, error) {
+	var periode sql.NullTime
+	err := c.DB.Raw("SELECT MAX(periode) FROM "+table+" WHERE periode BETWEEN ? AND ?",
+		start.Format("2006-01-02"), end.Format("2006-01-02")).Row().Scan(&periode)
+	if err != nil || !periode.Valid {
+		return nil, err
+	}
+	return &periode.Time, nil
+}
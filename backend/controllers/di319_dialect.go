@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"pipeline-backend/models"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Dialect sniffing for DI319 extracts. A single buffered peek of the stream is
+// used to decide encoding, BOM, delimiter and header row, so the source is read
+// exactly once.
+const (
+	di319SniffSampleSize = 512 * 1024
+	di319HeaderScanLines = 100 // SSRS reports can carry long preambles before the header
+	di319MinHeaderScore  = 2   // known fields a row must contain to count as a header
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// di319Delimiters - Candidate delimiters in tie-break order
+var di319Delimiters = []rune{';', ',', '\t', '|'}
+
+// di319Dialect - What the sniffer decided about one sheet / CSV entry
+type di319Dialect struct {
+	Source         string   `json:"source"`
+	Format         string   `json:"format"`             // csv, xlsx
+	Encoding       string   `json:"encoding,omitempty"` // utf-8, windows-1252
+	BOM            bool     `json:"bom"`
+	Delimiter      string   `json:"delimiter,omitempty"`
+	Quoted         bool     `json:"quoted"`
+	HeaderRow      int      `json:"header_row"`   // 1-based record number of the header
+	HeaderScore    int      `json:"header_score"` // known DI319 fields matched by the header
+	Profile        string   `json:"profile"`
+	Columns        int      `json:"columns"`
+	MatchedFields  []string `json:"matched_fields"`
+	MissingFields  []string `json:"missing_required_fields"`
+	SkippedPrelude int      `json:"skipped_preamble_rows"`
+}
+
+// di319HeaderCandidate - Best header row found for one delimiter
+type di319HeaderCandidate struct {
+	delimiter rune
+	row       int
+	score     int
+	header    []string
+	matched   []string
+	profile   *models.ImportProfile
+}
+
+// sniffDI319CSV - Detect the dialect and import profile of a CSV stream and return a sheet positioned after the header
+func sniffDI319CSV(name string, r io.Reader, profiles []*models.ImportProfile) (*di319Sheet, error) {
+	buffered := bufio.NewReaderSize(r, di319SniffSampleSize)
+	sample, err := buffered.Peek(di319SniffSampleSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("Failed to read file: %v", err)
+	}
+	complete := err == io.EOF
+	if len(sample) == 0 {
+		return nil, fmt.Errorf("File is empty")
+	}
+
+	dialect := di319Dialect{Source: name, Format: "csv", Encoding: "utf-8"}
+
+	// BOM
+	if bytes.HasPrefix(sample, utf8BOM) {
+		dialect.BOM = true
+		buffered.Discard(len(utf8BOM))
+		sample = sample[len(utf8BOM):]
+	}
+
+	// Encoding: anything that is not valid UTF-8 is treated as Windows-1252 (Excel's default on Indonesian Windows)
+	var text io.Reader = buffered
+	sampleText := string(trimPartialRune(sample))
+	if !utf8.ValidString(sampleText) {
+		dialect.Encoding = "windows-1252"
+		text = charmap.Windows1252.NewDecoder().Reader(buffered)
+		decoded, err := charmap.Windows1252.NewDecoder().Bytes(sample)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode file: %v", err)
+		}
+		sampleText = string(decoded)
+	}
+
+	// Only score complete lines - the last one in a partial sample may be cut off
+	if !complete {
+		if idx := strings.LastIndexByte(sampleText, '\n'); idx >= 0 {
+			sampleText = sampleText[:idx+1]
+		}
+	}
+
+	var best *di319HeaderCandidate
+	for _, delimiter := range di319Delimiters {
+		candidate := scoreDI319HeaderRows(sampleText, delimiter, profiles)
+		if candidate != nil && (best == nil || candidate.score > best.score ||
+			(candidate.score == best.score && len(candidate.header) > len(best.header))) {
+			best = candidate
+		}
+	}
+	if best == nil || best.score < di319MinHeaderScore {
+		return nil, fmt.Errorf("Failed to find CSV header: no row in the first %d matches the DI319 columns", di319HeaderScanLines)
+	}
+
+	dialect.Delimiter = string(best.delimiter)
+	dialect.Quoted = strings.Contains(sampleText, `"`)
+	dialect.HeaderRow = best.row + 1
+	dialect.HeaderScore = best.score
+	dialect.Profile = best.profile.Name
+	dialect.Columns = len(best.header)
+	dialect.MatchedFields = best.matched
+	dialect.MissingFields = missingDI319Fields(best.matched)
+	dialect.SkippedPrelude = best.row
+
+	// Position the real reader right after the header, surfacing any read error
+	reader := newDI319CSVReader(text, best.delimiter)
+	for i := 0; i <= best.row; i++ {
+		if _, err := reader.Read(); err != nil {
+			return nil, fmt.Errorf("Failed to read line %d: %v", i+1, err)
+		}
+	}
+
+	return &di319Sheet{
+		Name:        name,
+		Reader:      reader,
+		Header:      best.header,
+		HeaderIndex: best.row,
+		Dialect:     dialect,
+		Mapping:     newDI319Mapping(best.profile, best.header),
+	}, nil
+}
+
+// scoreDI319HeaderRows - Find the row and profile that match the most DI319 fields for one delimiter
+func scoreDI319HeaderRows(sample string, delimiter rune, profiles []*models.ImportProfile) *di319HeaderCandidate {
+	reader := newDI319CSVReader(strings.NewReader(sample), delimiter)
+
+	var best *di319HeaderCandidate
+	for row := 0; row < di319HeaderScanLines; row++ {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+
+		profile, matched := bestDI319Profile(line, profiles)
+		if best == nil || len(matched) > best.score {
+			best = &di319HeaderCandidate{delimiter: delimiter, row: row, score: len(matched), header: line, matched: matched, profile: profile}
+		}
+	}
+	return best
+}
+
+// missingDI319Fields - Required fields absent from a matched set
+func missingDI319Fields(matched []string) []string {
+	have := make(map[string]bool, len(matched))
+	for _, field := range matched {
+		have[field] = true
+	}
+
+	missing := []string{}
+	for _, field := range di319RequiredFields {
+		if !have[field] {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// trimPartialRune - Drop a multi-byte sequence cut off at the end of a sample
+func trimPartialRune(b []byte) []byte {
+	for i := 1; i <= utf8.UTFMax && i <= len(b); i++ {
+		if r, _ := utf8.DecodeLastRune(b[:len(b)-i+1]); r != utf8.RuneError {
+			return b[:len(b)-i+1]
+		}
+	}
+	return b
+}
+
+// newDI319CSVReader - CSV reader tolerant of ragged preamble rows and stray quotes
+func newDI319CSVReader(r io.Reader, delimiter rune) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	return reader
+}
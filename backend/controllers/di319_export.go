@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"pipeline-backend/models"
+	"pipeline-backend/pagination"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxMaxDataRows - Rows per sheet below the header (Excel's limit is 1,048,576 rows)
+const xlsxMaxDataRows = 1048575
+
+var di319ExportHeader = []string{
+	"Periode", "Main Branch", "Branch", "CIF", "No Rekening", "Type", "Nama", "PN Pengelola",
+	"Balance", "Aval Balance", "Avg Balance", "Open Date", "Drop Amount", "Drop %", "Qualifying Rule",
+}
+
+// Export - Stream the filtered DI319 list as xlsx or csv (GET /api/di319/export?format=xlsx|csv).
+// Takes the same filters, search and sort as GetAll (paging is ignored), limited to the user's data scope.
+// Rows are read with a cursor and written as they arrive; an audit entry records the export.
+func (c *DI319ImportController) Export(ctx *fiber.Ctx) error {
+	format := ctx.Query("format", "xlsx")
+	if format != "xlsx" && format != "csv" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid format: %s (expected xlsx or csv)", format),
+		})
+	}
+
+	params, err := pagination.Parse(ctx, di319ListSpec)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	addDI319RuleFilter(ctx, params.Filter)
+	if err := applyDI319DataScope(c.DB, ctx, params.Filter); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load data scope",
+		})
+	}
+
+	query := params.Filter.Apply(c.DB.Model(&models.DI319{})).Order(params.Keyset.OrderSQL())
+	userID, username := auditUser(ctx)
+	details := fmt.Sprintf("format=%s %s", format, ctx.Request().URI().QueryString())
+
+	filename := fmt.Sprintf("di319_%s.%s", time.Now().Format("20060102_150405"), format)
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if format == "csv" {
+		ctx.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	} else {
+		ctx.Set(fiber.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	}
+
+	// The body is produced after the handler returns, so nothing below may touch ctx
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		start := time.Now()
+		rows, err := query.Rows()
+		if err != nil {
+			log.Printf("❌ DI319 export failed: %v", err)
+			writeAuditLog(c.DB, userID, username, "export", "di319", details+" (failed: "+err.Error()+")", 0)
+			return
+		}
+		defer rows.Close()
+
+		var written int64
+		if format == "csv" {
+			written, err = c.writeDI319CSV(w, rows)
+		} else {
+			written, err = c.writeDI319XLSX(w, rows)
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			log.Printf("❌ DI319 export failed after %d rows: %v", written, err)
+			details += " (failed: " + err.Error() + ")"
+		} else {
+			log.Printf("📤 DI319 export (%s) of %d rows by %s in %v", format, written, username, time.Since(start))
+		}
+		writeAuditLog(c.DB, userID, username, "export", "di319", details, written)
+	})
+	return nil
+}
+
+// writeDI319CSV - One CSV line per row, amounts as plain decimals
+func (c *DI319ImportController) writeDI319CSV(w io.Writer, rows *sql.Rows) (int64, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(di319ExportHeader); err != nil {
+		return 0, err
+	}
+
+	var written int64
+	for rows.Next() {
+		var row models.DI319
+		if err := c.DB.ScanRows(rows, &row); err != nil {
+			return written, err
+		}
+		record := []string{
+			row.Periode.Format("2006-01-02"), row.MainBranch, row.Branch, row.CIF, row.NoRek, row.Type, row.Nama,
+			row.PNPengelola, row.Balance.String(), row.AvalBalance.String(), "", row.OpenDate.Format("2006-01-02"),
+			"", "", row.QualifyingRule,
+		}
+		if row.AvgBalance != nil {
+			record[10] = row.AvgBalance.String()
+		}
+		if row.DropAmount != nil {
+			record[12] = row.DropAmount.String()
+		}
+		if row.DropPct != nil {
+			record[13] = strconv.FormatFloat(*row.DropPct, 'f', 2, 64)
+		}
+		if err := writer.Write(record); err != nil {
+			return written, err
+		}
+		written++
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return written, err
+	}
+	return written, rows.Err()
+}
+
+// writeDI319XLSX - Stream rows into a workbook (excelize spills large sheets to a temp file),
+// continuing on a new sheet whenever one is full
+func (c *DI319ImportController) writeDI319XLSX(w io.Writer, rows *sql.Rows) (int64, error) {
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+
+	header := make([]interface{}, len(di319ExportHeader))
+	for i, title := range di319ExportHeader {
+		header[i] = title
+	}
+
+	var stream *excelize.StreamWriter
+	var written int64
+	sheetRow := 0
+	newSheet := func(number int) error {
+		name := "DI319"
+		if number > 1 {
+			name = fmt.Sprintf("DI319 (%d)", number)
+		}
+		if number == 1 {
+			if err := workbook.SetSheetName("Sheet1", name); err != nil {
+				return err
+			}
+		} else {
+			if err := stream.Flush(); err != nil {
+				return err
+			}
+			if _, err := workbook.NewSheet(name); err != nil {
+				return err
+			}
+		}
+		var err error
+		if stream, err = workbook.NewStreamWriter(name); err != nil {
+			return err
+		}
+		sheetRow = 1
+		return stream.SetRow("A1", header)
+	}
+	if err := newSheet(1); err != nil {
+		return 0, err
+	}
+
+	for rows.Next() {
+		var row models.DI319
+		if err := c.DB.ScanRows(rows, &row); err != nil {
+			return written, err
+		}
+		if sheetRow > xlsxMaxDataRows {
+			if err := newSheet(int(written/xlsxMaxDataRows) + 1); err != nil {
+				return written, err
+			}
+		}
+
+		values := []interface{}{
+			row.Periode.Format("2006-01-02"), row.MainBranch, row.Branch, row.CIF, row.NoRek, row.Type, row.Nama,
+			row.PNPengelola, row.Balance.Float(), row.AvalBalance.Float(), nil, row.OpenDate.Format("2006-01-02"),
+			nil, nil, row.QualifyingRule,
+		}
+		if row.AvgBalance != nil {
+			values[10] = row.AvgBalance.Float()
+		}
+		if row.DropAmount != nil {
+			values[12] = row.DropAmount.Float()
+		}
+		if row.DropPct != nil {
+			values[13] = *row.DropPct
+		}
+
+		sheetRow++
+		cell, _ := excelize.CoordinatesToCellName(1, sheetRow)
+		if err := stream.SetRow(cell, values); err != nil {
+			return written, err
+		}
+		written++
+	}
+	if err := rows.Err(); err != nil {
+		return written, err
+	}
+
+	if err := stream.Flush(); err != nil {
+		return written, err
+	}
+	return written, workbook.Write(w)
+}
+
+// ExportStats - The stats as a workbook (GET /api/stats/export): a Totals sheet plus one sheet per
+// dimension. Takes the GetStats parameters; group_by defaults to branch,type and limit to 1000 groups.
+// Limited to the user's data scope; an audit entry records the export.
+func (c *DI319ImportController) ExportStats(ctx *fiber.Ctx) error {
+	r, err := parseDI319StatsRequest(ctx, []string{"branch", "type"}, pagination.MaxPageSize)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := applyDI319DataScope(c.DB, ctx, r.Filter); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load data scope",
+		})
+	}
+
+	stats, failures := c.computeDI319Stats(r)
+	if len(failures) > 0 {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":    "Failed to compute stats",
+			"failures": failures,
+		})
+	}
+
+	workbook, rows, err := buildDI319StatsWorkbook(stats, r.Dimensions)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	defer workbook.Close()
+
+	userID, username := auditUser(ctx)
+	writeAuditLog(c.DB, userID, username, "export", "stats", string(ctx.Request().URI().QueryString()), rows)
+
+	filename := fmt.Sprintf("di319_stats_%s.xlsx", time.Now().Format("20060102_150405"))
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Set(fiber.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	return workbook.Write(ctx.Response().BodyWriter())
+}
+
+// buildDI319StatsWorkbook - Totals sheet plus one sheet per dimension; returns the number of group rows
+func buildDI319StatsWorkbook(stats *di319Stats, dimensions []string) (*excelize.File, int64, error) {
+	workbook := excelize.NewFile()
+	fail := func(err error) (*excelize.File, int64, error) {
+		workbook.Close()
+		return nil, 0, err
+	}
+
+	if err := workbook.SetSheetName("Sheet1", "Totals"); err != nil {
+		return fail(err)
+	}
+	totals := [][]interface{}{
+		{"Metric", "Value"},
+		{"Accounts", stats.Totals.Accounts},
+		{"Total Balance", stats.Totals.TotalBalance.Float()},
+		{"Total Aval Balance", stats.Totals.TotalAvalBalance.Float()},
+		{"Total Avg Balance", stats.Totals.TotalAvgBalance.Float()},
+		{"Drop Candidates", stats.Totals.DropCount},
+		{"Total Drop Amount", stats.Totals.TotalDropAmount.Float()},
+		{"Avg Drop %", optionalFloat(stats.Totals.AvgDropPct)},
+	}
+	for i, values := range totals {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := workbook.SetSheetRow("Totals", cell, &values); err != nil {
+			return fail(err)
+		}
+	}
+
+	var rows int64
+	for _, name := range dimensions {
+		if _, err := workbook.NewSheet(name); err != nil {
+			return fail(err)
+		}
+		header := []interface{}{name, "Label", "Accounts", "Total Balance", "Total Aval Balance",
+			"Total Avg Balance", "Drop Candidates", "Total Drop Amount", "Avg Drop %"}
+		if err := workbook.SetSheetRow(name, "A1", &header); err != nil {
+			return fail(err)
+		}
+		for i, group := range stats.Dimensions[name] {
+			values := []interface{}{group.Name, group.Label, group.Accounts, group.TotalBalance.Float(),
+				group.TotalAvalBalance.Float(), group.TotalAvgBalance.Float(), group.DropCount,
+				group.TotalDropAmount.Float(), optionalFloat(group.AvgDropPct)}
+			cell, _ := excelize.CoordinatesToCellName(1, i+2)
+			if err := workbook.SetSheetRow(name, cell, &values); err != nil {
+				return fail(err)
+			}
+			rows++
+		}
+	}
+	return workbook, rows, nil
+}
+
+// optionalFloat - The value, or nil for an empty cell
+func optionalFloat(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
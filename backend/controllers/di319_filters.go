@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"pipeline-backend/pagination"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// di319FilterFields - Structured filters shared by DI319 listings and stats:
+//   - branch, main_branch, type, pn_pengelola: comma separated values, e.g. branch=10272,10168
+//   - periode, open_date: one date; periode_from/to, open_date_from/to: inclusive ranges (yyyy-mm-dd)
+//   - balance_min/max, aval_balance_min/max, avg_balance_min/max, drop_amount_min/max: any amount
+//     format, e.g. 1.000.000 or 1,000,000.50
+//   - drop_pct_min/max in percent
+var di319FilterFields = []pagination.FilterField{
+	{Param: "branch", Column: "branch", Kind: pagination.FilterIn},
+	{Param: "main_branch", Column: "main_branch", Kind: pagination.FilterIn},
+	{Param: "type", Column: "type", Kind: pagination.FilterIn},
+	{Param: "pn_pengelola", Column: "pn_pengelola", Kind: pagination.FilterIn},
+	{Param: "periode", Column: "periode", Kind: pagination.FilterDateRange},
+	{Param: "open_date", Column: "open_date", Kind: pagination.FilterDateRange},
+	{Param: "balance", Column: "balance", Kind: pagination.FilterAmountRange},
+	{Param: "aval_balance", Column: "aval_balance", Kind: pagination.FilterAmountRange},
+	{Param: "avg_balance", Column: "avg_balance", Kind: pagination.FilterAmountRange},
+	{Param: "drop_amount", Column: "drop_amount", Kind: pagination.FilterAmountRange},
+	{Param: "drop_pct", Column: "drop_pct", Kind: pagination.FilterNumberRange},
+}
+
+// di319ListSpec - Paging, sorting and filtering of GET /api/di319
+var di319ListSpec = pagination.Spec{
+	Table: "di319",
+	Sorts: map[string]pagination.Sort{
+		"periode":      {Column: "periode"},
+		"open_date":    {Column: "open_date"},
+		"branch":       {Column: "branch"},
+		"main_branch":  {Column: "main_branch"},
+		"type":         {Column: "type"},
+		"nama":         {Column: "nama"},
+		"pn_pengelola": {Column: "pn_pengelola"},
+		"balance":      {Column: "balance"},
+		"aval_balance": {Column: "aval_balance"},
+		"avg_balance":  {Column: "avg_balance", Nullable: true},
+		"drop_amount":  {Column: "drop_amount", Nullable: true},
+		"drop_pct":     {Column: "drop_pct", Nullable: true},
+	},
+	DefaultSort:   "periode",
+	DefaultDesc:   true,
+	Filters:       di319FilterFields,
+	SearchColumns: []string{"branch", "nama", "norek", "cif"},
+	Legacy: func(m pagination.Meta) fiber.Map {
+		return fiber.Map{
+			"pagination": fiber.Map{
+				"total_records":     m.Total,
+				"total_pages":       m.TotalPages,
+				"current_page":      m.Page,
+				"page_size":         m.PageSize,
+				"total_is_estimate": m.TotalIsEstimate,
+				"next_cursor":       m.NextCursor,
+				"has_more":          m.HasMore,
+			},
+		}
+	},
+}
+
+// parseDI319Filters - The DI319 filters plus rule (a qualifying rule, or "none" for rows that did not qualify)
+func parseDI319Filters(ctx *fiber.Ctx) (*pagination.Filter, error) {
+	filter, err := pagination.ParseFilters(ctx, di319FilterFields)
+	if err != nil {
+		return nil, err
+	}
+	addDI319RuleFilter(ctx, filter)
+	return filter, nil
+}
+
+// addDI319RuleFilter - ?rule=<qualifying rule>|none
+func addDI319RuleFilter(ctx *fiber.Ctx, filter *pagination.Filter) {
+	switch rule := ctx.Query("rule"); rule {
+	case "":
+	case "none":
+		filter.Add("qualifying_rule = ''")
+	default:
+		filter.Add("qualifying_rule = ?", rule)
+	}
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"pipeline-backend/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// di319HistoryPoint - An account in one imported periode. Present is false when the account has
+// no row for that periode (in di319 mode: it did not qualify, or was missing from the extract).
+type di319HistoryPoint struct {
+	di319TrendPoint
+	Present        bool `json:"present"`
+	BelowThreshold bool `json:"below_threshold"`
+}
+
+// GetAccountHistory - Balance time series of one account over every imported periode
+// (GET /api/di319/accounts/:norek/history), with the first drop and the current drop streak
+func (c *DI319ImportController) GetAccountHistory(ctx *fiber.Ctx) error {
+	norek := ctx.Params("norek")
+	scope, err := di319ScopeFilter(c.DB, ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load data scope",
+		})
+	}
+
+	// The full snapshot has every periode of the account, dropped or not; periodes imported without it
+	// only have the di319 candidate rows
+	hasSnapshot := c.DB.Migrator().HasTable(&models.DI319Snapshot{})
+	var snapshotRows []models.DI319Snapshot
+	if hasSnapshot {
+		if err := scope.Apply(c.DB.Where("norek = ?", norek)).Find(&snapshotRows).Error; err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+	var candidateRows []models.DI319
+	if err := scope.Apply(c.DB.Where("norek = ?", norek)).Find(&candidateRows).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	rows, source := mergeDI319Sources(snapshotRows, candidateRows)
+	if len(rows) == 0 {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Account not found",
+		})
+	}
+
+	// Every periode imported (into either table) since the account first appeared, so gaps show up in the series
+	first := rows[0].Periode.Format("2006-01-02")
+	query, args := "SELECT DISTINCT periode FROM di319 WHERE periode >= ?", []interface{}{first}
+	if hasSnapshot {
+		query += " UNION SELECT DISTINCT periode FROM di319_snapshot WHERE periode >= ?"
+		args = append(args, first)
+	}
+	var periodes []time.Time
+	if err := c.DB.Raw(query+" ORDER BY periode", args...).Scan(&periodes).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// One row per periode (mergeDI319Sources already dropped duplicates)
+	byPeriode := make(map[time.Time]models.DI319, len(rows))
+	for _, row := range rows {
+		byPeriode[row.Periode] = row
+	}
+
+	history := make([]di319HistoryPoint, 0, len(periodes))
+	var firstDrop, streakStart *time.Time
+	streak := 0
+	for _, periode := range periodes {
+		periode := periode
+		point := di319HistoryPoint{di319TrendPoint: di319TrendPoint{Periode: periode}}
+		if row, ok := byPeriode[periode]; ok {
+			point.di319TrendPoint = newDI319TrendPoint(row)
+			point.Present = true
+			point.BelowThreshold = shouldCreatePipeline(row)
+		}
+		history = append(history, point)
+
+		if !point.BelowThreshold {
+			streak, streakStart = 0, nil
+			continue
+		}
+		if firstDrop == nil {
+			firstDrop = &periode
+		}
+		if streak == 0 {
+			streakStart = &periode
+		}
+		streak++
+	}
+
+	latest := rows[len(rows)-1]
+	return ctx.JSON(fiber.Map{
+		"norek":                norek,
+		"cif":                  latest.CIF,
+		"nama":                 latest.Nama,
+		"type":                 latest.Type,
+		"branch":               latest.Branch,
+		"source":               source,
+		"first_drop_periode":   firstDrop,
+		"current_streak_start": streakStart,
+		// Consecutive periodes, up to the latest import, the account has stayed below threshold
+		"consecutive_periods_below_threshold": streak,
+		"history":                             history,
+	})
+}
@@ -0,0 +1,385 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"pipeline-backend/models"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// DI319 import pipeline: parse → validate/filter → batch → N insert workers.
+// Channels between stages are bounded so a slow database throttles the reader
+// instead of buffering the whole file in memory.
+const (
+	di319DefaultWorkers   = 8
+	di319MaxWorkers       = 32
+	di319DefaultBatchSize = 2000
+	di319MaxBatchSize     = 10000
+	di319InsertChunkSize  = 1000 // rows per INSERT statement, keeps placeholders under MySQL's limit
+)
+
+// DI319 write strategies
+const (
+	di319StrategyBatch    = "batch"     // GORM batch inserts from a worker pool
+	di319StrategyLoadData = "load_data" // LOAD DATA LOCAL INFILE into a staging table
+)
+
+// di319ImportOptions - Tuning knobs for one DI319 import run
+type di319ImportOptions struct {
+	Workers   int
+	BatchSize int
+	Strategy  string
+	Snapshot  bool // also keep every valid row in di319_snapshot
+}
+
+// di319Line - One raw CSV line read from the upload
+type di319Line struct {
+	sheet  int
+	number int
+	record []string
+	err    error
+}
+
+// di319Batch - A sequenced batch of filtered records (and, in snapshot mode, all records) ready for insert
+type di319Batch struct {
+	seq      int
+	records  []models.DI319
+	snapshot []models.DI319Snapshot
+}
+
+// di319BatchResult - Outcome of inserting one batch
+type di319BatchResult struct {
+	seq         int
+	inserted    int
+	failed      int
+	snapshotted int
+}
+
+// add - Queue a parsed record: candidates go to di319, everything to the snapshot when enabled
+func (b *di319Batch) add(record models.DI319, snapshot bool) {
+	if shouldCreatePipeline(record) {
+		b.records = append(b.records, record)
+	}
+	if snapshot {
+		b.snapshot = append(b.snapshot, toDI319Snapshot(record))
+	}
+}
+
+// full - Whether the batch reached the configured size
+func (b *di319Batch) full(batchSize int) bool {
+	return len(b.records) >= batchSize || len(b.snapshot) >= batchSize
+}
+
+// empty - Whether there is nothing to insert
+func (b *di319Batch) empty() bool {
+	return len(b.records) == 0 && len(b.snapshot) == 0
+}
+
+// loadDI319ImportOptions - Read worker/batch settings from env, overridable per request
+func loadDI319ImportOptions(ctx *fiber.Ctx) di319ImportOptions {
+	opts := di319ImportOptions{
+		Workers:   envInt("DI319_IMPORT_WORKERS", di319DefaultWorkers),
+		BatchSize: envInt("DI319_IMPORT_BATCH_SIZE", di319DefaultBatchSize),
+		Strategy:  os.Getenv("DI319_IMPORT_STRATEGY"),
+	}
+
+	if workers, err := strconv.Atoi(ctx.FormValue("workers")); err == nil {
+		opts.Workers = workers
+	}
+	if batchSize, err := strconv.Atoi(ctx.FormValue("batch_size")); err == nil {
+		opts.BatchSize = batchSize
+	}
+	if strategy := ctx.FormValue("strategy"); strategy != "" {
+		opts.Strategy = strategy
+	}
+	opts.Snapshot, _ = strconv.ParseBool(os.Getenv("DI319_IMPORT_SNAPSHOT"))
+	if snapshot, err := strconv.ParseBool(ctx.FormValue("snapshot")); err == nil {
+		opts.Snapshot = snapshot
+	}
+	if opts.Strategy != di319StrategyLoadData {
+		opts.Strategy = di319StrategyBatch
+	}
+
+	opts.Workers = clampInt(opts.Workers, 1, di319MaxWorkers)
+	opts.BatchSize = clampInt(opts.BatchSize, 1, di319MaxBatchSize)
+	return opts
+}
+
+// runDI319Import - Run the full import pipeline with the selected write strategy
+func (c *DI319ImportController) runDI319Import(sheets []*di319Sheet, opts di319ImportOptions) {
+	startTime := time.Now()
+
+	if opts.Strategy == di319StrategyLoadData && !c.localInfileEnabled() {
+		log.Println("⚠️  local_infile is disabled on the server, falling back to batch inserts")
+		opts.Strategy = di319StrategyBatch
+	}
+
+	di319ImportMutex.Lock()
+	di319ImportWorkers = opts.Workers
+	di319ImportStrategy = opts.Strategy
+	di319ImportSnapshot = opts.Snapshot
+	di319ImportMutex.Unlock()
+
+	touched := &di319PeriodeSet{}
+	readErr := make(chan error, 1)
+	batches := c.startDI319Parser(sheets, opts, touched, readErr)
+
+	var inserted, failed int
+	var err error
+	if opts.Strategy == di319StrategyLoadData {
+		inserted, failed, err = c.loadDI319ViaInfile(batches, opts)
+	} else {
+		inserted, failed = c.insertDI319Batches(batches, opts)
+	}
+	// The reader is done once the batch stream is drained; a broken upload fails the import
+	if err == nil {
+		select {
+		case err = <-readErr:
+		default:
+		}
+	}
+
+	// Rows written before a failure still count, so refresh the summary either way
+	summaryNote := c.refreshDI319SummaryAfterImport(touched)
+
+	if err != nil {
+		di319ImportMutex.Lock()
+		di319ImportStatus = "error"
+		di319ImportMessage = fmt.Sprintf("Import failed: %v", err)
+		di319ImportMutex.Unlock()
+		log.Printf("❌ DI319 import failed: %v", err)
+		return
+	}
+
+	// Calculate filter percentage
+	di319ImportMutex.Lock()
+	di319PipelineCreated = inserted + failed // Records that met ≥50% criteria
+	if di319ImportTotal > 0 {
+		di319FilteredPercentage = (float64(di319ImportProgress) / float64(di319ImportTotal)) * 100
+	}
+	di319ImportStatus = "completed"
+	di319ImportMessage = fmt.Sprintf("Import completed! Processed %d records, saved %d records with ≥50%% drop (%.2f%% filtered)",
+		di319ImportTotal, di319ImportProgress, di319FilteredPercentage)
+	if failed > 0 {
+		di319ImportMessage += fmt.Sprintf(", %d records failed to insert", failed)
+	}
+	if opts.Snapshot {
+		di319ImportMessage += fmt.Sprintf(", %d records kept in the full snapshot", di319SnapshotRows)
+	}
+	di319ImportMessage += summaryNote
+	if notifyNewCandidatesEnabled() {
+		di319ImportMessage += ", new candidate notifications are being sent"
+	}
+	di319ImportMutex.Unlock()
+
+	go NotifyNewCandidatesAfterImport(c.DB, touched.list())
+
+	log.Println(di319ImportMessage)
+	log.Printf("✅ DI319 Import (%s) completed in %v", opts.Strategy, time.Since(startTime))
+}
+
+// startDI319Parser - Start the read and parse/filter stages, returning the batch stream.
+// Every periode that reaches a batch is recorded in touched. Malformed CSV lines are skipped; any other
+// read error (truncated gzip, corrupt zip entry or xlsx) stops reading and is sent on readErr.
+func (c *DI319ImportController) startDI319Parser(sheets []*di319Sheet, opts di319ImportOptions, touched *di319PeriodeSet, readErr chan<- error) <-chan di319Batch {
+	lines := make(chan di319Line, opts.BatchSize)
+	batches := make(chan di319Batch, opts.Workers)
+
+	// Stage 1: read rows, one sheet / CSV entry after another
+	go func() {
+		defer close(lines)
+		for i, sheet := range sheets {
+			lineNumber := sheet.HeaderIndex + 1
+			for {
+				record, err := sheet.Reader.Read()
+				if err == io.EOF {
+					break
+				}
+				lineNumber++
+				var parseErr *csv.ParseError
+				if err != nil && !errors.As(err, &parseErr) {
+					// Decompression and archive errors are sticky: every further Read returns the same error
+					readErr <- fmt.Errorf("%s: line %d: %w", sheet.Name, lineNumber, err)
+					return
+				}
+				lines <- di319Line{sheet: i, number: lineNumber, record: record, err: err}
+			}
+		}
+	}()
+
+	// Stage 2: parse, validate, filter and group into batches
+	go func() {
+		defer close(batches)
+		seq := 0
+		batch := di319Batch{}
+		periodes := make(map[time.Time]error)
+
+		for line := range lines {
+			di319ImportMutex.Lock()
+			di319ImportTotal++
+			di319ImportMutex.Unlock()
+
+			if line.err != nil {
+				log.Printf("%s: error reading line %d: %v", sheets[line.sheet].Name, line.number, line.err)
+				countDI319Skipped()
+				continue
+			}
+
+			di319, err := parseDI319Record(line.record, sheets[line.sheet].Mapping)
+			if err != nil {
+				log.Printf("%s: line %d: %v", sheets[line.sheet].Name, line.number, err)
+				countDI319Skipped()
+				continue
+			}
+
+			// Snapshot mode: prepare each periode once, before any of its rows are queued
+			if opts.Snapshot {
+				err, seen := periodes[di319.Periode]
+				if !seen {
+					err = c.prepareDI319SnapshotPeriode(di319.Periode)
+					periodes[di319.Periode] = err
+					if err != nil {
+						log.Printf("❌ Snapshot periode %s: %v", di319.Periode.Format("2006-01-02"), err)
+					}
+				}
+				if err != nil {
+					countDI319Skipped()
+					continue
+				}
+			}
+
+			// Only rows with a balance drop >= 50% go to DI319; the snapshot keeps all of them
+			batch.add(di319, opts.Snapshot)
+			touched.add(di319.Periode)
+
+			if batch.full(opts.BatchSize) {
+				batch.seq = seq
+				batches <- batch
+				seq++
+				batch = di319Batch{}
+			}
+		}
+
+		// Flush the tail
+		if !batch.empty() {
+			batch.seq = seq
+			batches <- batch
+		}
+	}()
+
+	return batches
+}
+
+// insertDI319Batches - Stage 3 of the batch strategy: N insert workers with in-order progress
+func (c *DI319ImportController) insertDI319Batches(batches <-chan di319Batch, opts di319ImportOptions) (int, int) {
+	results := make(chan di319BatchResult, opts.Workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			for batch := range batches {
+				results <- c.insertDI319Batch(workerID, batch)
+			}
+		}(i)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Apply results strictly in sequence so imported_rows never skips ahead
+	pending := make(map[int]di319BatchResult)
+	next := 0
+	inserted := 0
+	failed := 0
+	for result := range results {
+		pending[result.seq] = result
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			inserted += r.inserted
+			failed += r.failed
+
+			di319ImportMutex.Lock()
+			di319ImportProgress = inserted
+			di319ImportFailed = failed
+			di319SnapshotRows += r.snapshotted
+			di319ImportMutex.Unlock()
+		}
+	}
+
+	return inserted, failed
+}
+
+// insertDI319Batch - Insert one batch (candidates and snapshot rows together), reporting how many rows landed
+func (c *DI319ImportController) insertDI319Batch(workerID int, batch di319Batch) di319BatchResult {
+	result := di319BatchResult{seq: batch.seq}
+	if batch.empty() {
+		return result
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if len(batch.records) > 0 {
+			if err := tx.CreateInBatches(batch.records, di319InsertChunkSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(batch.snapshot) > 0 {
+			if err := tx.CreateInBatches(batch.snapshot, di319InsertChunkSize).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("❌ Worker %d: DI319 batch %d insert failed: %v", workerID, batch.seq, err)
+		result.failed = len(batch.records)
+		return result
+	}
+
+	result.inserted = len(batch.records)
+	result.snapshotted = len(batch.snapshot)
+	return result
+}
+
+// countDI319Skipped - Record one unreadable or invalid line
+func countDI319Skipped() {
+	di319ImportMutex.Lock()
+	di319ImportSkipped++
+	di319ImportMutex.Unlock()
+}
+
+// envInt - Read an integer environment variable with a default
+func envInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return def
+}
+
+// clampInt - Keep v within [min, max]
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
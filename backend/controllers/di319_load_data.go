@@ -0,0 +1,360 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"pipeline-backend/models"
+	"pipeline-backend/money"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// di319StagingColumns - Column order shared by the staging file, staging table and final INSERT ... SELECT
+var di319StagingColumns = []string{
+	"periode", "main_branch", "branch", "cif", "norek", "type", "nama",
+	"pn_pengelola", "balance", "aval_balance", "avg_balance", "open_date",
+	"drop_amount", "drop_pct", "qualifying_rule",
+}
+
+// localInfileEnabled - Check whether the server accepts LOAD DATA LOCAL INFILE
+func (c *DI319ImportController) localInfileEnabled() bool {
+	var enabled int
+	if err := c.DB.Raw("SELECT @@GLOBAL.local_infile").Scan(&enabled).Error; err != nil {
+		log.Printf("⚠️  Failed to read local_infile setting: %v", err)
+		return false
+	}
+	return enabled == 1
+}
+
+// loadDI319ViaInfile - Write filtered rows (all rows in snapshot mode) to a staging file, LOAD DATA it
+// into a temporary staging table and move it into di319 with one set-based INSERT ... SELECT
+func (c *DI319ImportController) loadDI319ViaInfile(batches <-chan di319Batch, opts di319ImportOptions) (int, int, error) {
+	stagingPath, staged, candidates, err := writeDI319StagingFile(batches, opts.Snapshot)
+	if stagingPath != "" {
+		defer os.Remove(stagingPath)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	di319ImportMutex.Lock()
+	di319ImportMessage = fmt.Sprintf("Loading %d staged records...", staged)
+	di319ImportMutex.Unlock()
+
+	if staged == 0 {
+		return 0, 0, nil
+	}
+
+	inserted, snapshotted, err := c.loadDI319StagingFile(stagingPath, opts.Snapshot)
+	if err != nil {
+		if !isLocalInfileRejected(err) {
+			return 0, candidates, err
+		}
+
+		// Server refused the local file after all - replay the staging file through the batch path
+		log.Printf("⚠️  LOAD DATA rejected (%v), falling back to batch inserts", err)
+		di319ImportMutex.Lock()
+		di319ImportStrategy = di319StrategyBatch
+		di319ImportMutex.Unlock()
+
+		replay, err := replayDI319StagingFile(stagingPath, opts)
+		if err != nil {
+			return 0, candidates, err
+		}
+		inserted, failed := c.insertDI319Batches(replay, opts)
+		return inserted, failed, nil
+	}
+
+	di319ImportMutex.Lock()
+	di319ImportProgress = inserted
+	di319SnapshotRows = snapshotted
+	di319ImportMutex.Unlock()
+
+	return inserted, candidates - inserted, nil
+}
+
+// loadDI319StagingFile - LOAD DATA into a connection-scoped temporary table, then INSERT ... SELECT in a transaction.
+// Runs on a raw *sql.Conn: LOAD DATA is not supported by the prepared statement protocol GORM is configured with.
+// In snapshot mode the staging table holds every row; candidates are picked out by qualifying_rule.
+func (c *DI319ImportController) loadDI319StagingFile(stagingPath string, snapshot bool) (int, int, error) {
+	// Stream the file through a named reader handler: the statement then only carries the handler name
+	// ("Reader::di319_<n>.tsv", from the CreateTemp pattern), never the staging path with whatever quotes
+	// or backslashes the directory holds
+	file, err := os.Open(stagingPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+	readerName := filepath.Base(stagingPath)
+	mysql.RegisterReaderHandler(readerName, func() io.Reader { return file })
+	defer mysql.DeregisterReaderHandler(readerName)
+
+	sqlDB, err := c.DB.DB()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Temporary tables only live on one connection, so pin it for the whole load
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "DROP TEMPORARY TABLE IF EXISTS di319_staging"); err != nil {
+		return 0, 0, err
+	}
+	if _, err := conn.ExecContext(ctx, di319StagingTableSQL()); err != nil {
+		return 0, 0, err
+	}
+	defer conn.ExecContext(ctx, "DROP TEMPORARY TABLE IF EXISTS di319_staging")
+
+	columns := strings.Join(di319StagingColumns, ", ")
+	loadSQL := fmt.Sprintf("LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE di319_staging "+
+		"CHARACTER SET utf8mb4 FIELDS TERMINATED BY '\\t' ESCAPED BY '\\\\' LINES TERMINATED BY '\\n' (%s)",
+		readerName, columns)
+	if _, err := conn.ExecContext(ctx, loadSQL); err != nil {
+		return 0, 0, err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	insertSQL := fmt.Sprintf("INSERT INTO di319 (%s) SELECT %s FROM di319_staging", columns, columns)
+	if snapshot {
+		insertSQL += " WHERE qualifying_rule <> ''"
+	}
+	result, err := tx.ExecContext(ctx, insertSQL)
+	if err != nil {
+		tx.Rollback()
+		return 0, 0, err
+	}
+	inserted, _ := result.RowsAffected()
+
+	var snapshotted int64
+	if snapshot {
+		result, err = tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO di319_snapshot (%s) SELECT %s FROM di319_staging", columns, columns))
+		if err != nil {
+			tx.Rollback()
+			return 0, 0, err
+		}
+		snapshotted, _ = result.RowsAffected()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return int(inserted), int(snapshotted), nil
+}
+
+// di319StagingTableSQL - The staging table takes di319's column types but none of its indexes: a table that is
+// read once needs none, and CREATE ... LIKE would copy index kinds InnoDB refuses on temporary tables
+func di319StagingTableSQL() string {
+	return fmt.Sprintf("CREATE TEMPORARY TABLE di319_staging SELECT %s FROM di319 LIMIT 0",
+		strings.Join(di319StagingColumns, ", "))
+}
+
+// writeDI319StagingFile - Drain the batch stream into a tab-separated file in LOAD DATA's default format.
+// Returns the rows staged and how many of them are di319 candidates.
+func writeDI319StagingFile(batches <-chan di319Batch, snapshot bool) (string, int, int, error) {
+	stagingDir := os.Getenv("DI319_STAGING_DIR")
+	if stagingDir == "" {
+		stagingDir = "tmp"
+	}
+	if err := os.MkdirAll(stagingDir, 0o755); err != nil {
+		drainDI319Batches(batches)
+		return "", 0, 0, fmt.Errorf("failed to create staging dir: %w", err)
+	}
+
+	file, err := os.CreateTemp(stagingDir, "di319_*.tsv")
+	if err != nil {
+		drainDI319Batches(batches)
+		return "", 0, 0, fmt.Errorf("failed to create staging file: %w", err)
+	}
+	stagingPath, _ := filepath.Abs(file.Name())
+
+	writer := bufio.NewWriterSize(file, 1<<20)
+	staged, candidates := 0, 0
+	var writeErr error
+	for batch := range batches {
+		if writeErr != nil {
+			continue // keep draining so the parser stages can finish
+		}
+		candidates += len(batch.records)
+
+		records := batch.records
+		if snapshot {
+			records = make([]models.DI319, len(batch.snapshot))
+			for i, row := range batch.snapshot {
+				records[i] = row.DI319
+			}
+		}
+		for _, record := range records {
+			if _, writeErr = writer.WriteString(encodeDI319StagingRow(record)); writeErr != nil {
+				break
+			}
+			staged++
+		}
+	}
+
+	if writeErr == nil {
+		writeErr = writer.Flush()
+	}
+	if closeErr := file.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		return stagingPath, 0, 0, fmt.Errorf("failed to write staging file: %w", writeErr)
+	}
+
+	return stagingPath, staged, candidates, nil
+}
+
+// replayDI319StagingFile - Read the staging file back as batches for the fallback path
+func replayDI319StagingFile(stagingPath string, opts di319ImportOptions) (<-chan di319Batch, error) {
+	file, err := os.Open(stagingPath)
+	if err != nil {
+		return nil, err
+	}
+
+	batches := make(chan di319Batch, 2)
+	go func() {
+		defer close(batches)
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1<<20)
+		seq := 0
+		batch := di319Batch{}
+		for scanner.Scan() {
+			record, err := decodeDI319StagingRow(scanner.Text())
+			if err != nil {
+				log.Printf("Staging row skipped: %v", err)
+				continue
+			}
+			batch.add(record, opts.Snapshot)
+			if batch.full(opts.BatchSize) {
+				batch.seq = seq
+				batches <- batch
+				seq++
+				batch = di319Batch{}
+			}
+		}
+		if !batch.empty() {
+			batch.seq = seq
+			batches <- batch
+		}
+	}()
+
+	return batches, nil
+}
+
+// encodeDI319StagingRow - One staging line, columns in di319StagingColumns order
+func encodeDI319StagingRow(d models.DI319) string {
+	avgBalance := `\N`
+	if d.AvgBalance != nil {
+		avgBalance = d.AvgBalance.String()
+	}
+	dropAmount, dropPct := `\N`, `\N`
+	if d.DropAmount != nil && d.DropPct != nil {
+		dropAmount = d.DropAmount.String()
+		dropPct = strconv.FormatFloat(*d.DropPct, 'f', 2, 64)
+	}
+
+	fields := []string{
+		d.Periode.Format("2006-01-02"),
+		stagingText(d.MainBranch),
+		stagingText(d.Branch),
+		stagingText(d.CIF),
+		stagingText(d.NoRek),
+		stagingText(d.Type),
+		stagingText(d.Nama),
+		stagingText(d.PNPengelola),
+		d.Balance.String(),
+		d.AvalBalance.String(),
+		avgBalance,
+		d.OpenDate.Format("2006-01-02"),
+		dropAmount,
+		dropPct,
+		stagingText(d.QualifyingRule),
+	}
+	return strings.Join(fields, "\t") + "\n"
+}
+
+// decodeDI319StagingRow - Inverse of encodeDI319StagingRow
+func decodeDI319StagingRow(line string) (models.DI319, error) {
+	d := models.DI319{}
+	fields := strings.Split(line, "\t")
+	if len(fields) != len(di319StagingColumns) {
+		return d, fmt.Errorf("expected %d columns, got %d", len(di319StagingColumns), len(fields))
+	}
+	for i := range fields {
+		fields[i] = strings.ReplaceAll(fields[i], `\\`, `\`)
+	}
+
+	var err error
+	if d.Periode, err = time.Parse("2006-01-02", fields[0]); err != nil {
+		return d, err
+	}
+	d.MainBranch = fields[1]
+	d.Branch = fields[2]
+	d.CIF = fields[3]
+	d.NoRek = fields[4]
+	d.Type = fields[5]
+	d.Nama = fields[6]
+	d.PNPengelola = fields[7]
+	if d.Balance, err = money.ParseHint(fields[8], '.'); err != nil {
+		return d, err
+	}
+	if d.AvalBalance, err = money.ParseHint(fields[9], '.'); err != nil {
+		return d, err
+	}
+	if fields[10] != `\N` {
+		avgBalance, err := money.ParseHint(fields[10], '.')
+		if err != nil {
+			return d, err
+		}
+		d.AvgBalance = &avgBalance
+	}
+	if d.OpenDate, err = time.Parse("2006-01-02", fields[11]); err != nil {
+		return d, err
+	}
+	// Drop metrics (fields 12-14) are derived, so recompute rather than parse them back
+	applyDI319DropMetrics(&d)
+	return d, nil
+}
+
+// stagingText - Escape a text value for LOAD DATA (tabs/newlines are never meaningful in DI319 fields)
+func stagingText(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\t", " ")
+	s = strings.ReplaceAll(s, "\r", " ")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// isLocalInfileRejected - True for the errors MySQL/driver raise when local infile is not allowed
+func isLocalInfileRejected(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		// 1148: command not allowed with this MySQL version, 3948: loading local data is disabled
+		return mysqlErr.Number == 1148 || mysqlErr.Number == 3948
+	}
+	return strings.Contains(err.Error(), "local file") && strings.Contains(err.Error(), "not registered")
+}
+
+// drainDI319Batches - Consume a batch stream without writing it
+func drainDI319Batches(batches <-chan di319Batch) {
+	for range batches {
+	}
+}
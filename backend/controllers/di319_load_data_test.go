@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"pipeline-backend/models"
+	"pipeline-backend/money"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDI319StagingTableSQL(t *testing.T) {
+	ddl := di319StagingTableSQL()
+	if strings.Contains(ddl, " LIKE ") {
+		t.Fatalf("staging table must not copy di319's indexes: %s", ddl)
+	}
+	if !strings.HasPrefix(ddl, "CREATE TEMPORARY TABLE di319_staging ") {
+		t.Fatalf("unexpected DDL: %s", ddl)
+	}
+	for _, column := range di319StagingColumns {
+		if !strings.Contains(ddl, column) {
+			t.Errorf("staging table is missing column %s: %s", column, ddl)
+		}
+	}
+}
+
+func TestDI319StagingRowRoundTrip(t *testing.T) {
+	avg := money.FromInt(1000)
+	lowAvg := money.FromMinor(1)
+	tests := []struct {
+		name string
+		row  models.DI319
+		want models.DI319 // text fields after escaping; zero means same as row
+	}{
+		{
+			name: "drop candidate",
+			row: models.DI319{NoRek: "001", Nama: "BUDI", Balance: money.FromInt(400), AvalBalance: money.FromInt(400),
+				AvgBalance: &avg},
+		},
+		{
+			name: "no average balance",
+			row:  models.DI319{NoRek: "002", Nama: "SITI", Balance: money.FromMinor(-12345), AvalBalance: 0},
+		},
+		{
+			name: "tiny average, drop clamped",
+			row:  models.DI319{NoRek: "003", Balance: money.FromInt(-5000000), AvgBalance: &lowAvg},
+		},
+		{
+			name: "backslashes survive, tabs and newlines become spaces",
+			row:  models.DI319{NoRek: `004\N`, Nama: "PT A\tB\r\nC", Type: `\\`, Balance: money.FromInt(1)},
+			want: models.DI319{NoRek: `004\N`, Nama: "PT A B  C", Type: `\\`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := tt.row
+			row.Periode = time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+			row.OpenDate = time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC)
+			row.MainBranch, row.Branch, row.CIF, row.PNPengelola = "KC JAKARTA", "00001", "CIF1", "PN000001"
+			applyDI319DropMetrics(&row)
+
+			line := encodeDI319StagingRow(row)
+			if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+				t.Fatalf("not a single line: %q", line)
+			}
+			got, err := decodeDI319StagingRow(strings.TrimSuffix(line, "\n"))
+			if err != nil {
+				t.Fatalf("decode %q: %v", line, err)
+			}
+
+			want := row
+			if tt.want.NoRek != "" {
+				want.NoRek, want.Nama, want.Type = tt.want.NoRek, tt.want.Nama, tt.want.Type
+			}
+			if got.NoRek != want.NoRek || got.Nama != want.Nama || got.Type != want.Type ||
+				got.MainBranch != want.MainBranch || got.Branch != want.Branch || got.CIF != want.CIF ||
+				got.PNPengelola != want.PNPengelola {
+				t.Errorf("text fields = %+v, want %+v", got, want)
+			}
+			if !got.Periode.Equal(want.Periode) || !got.OpenDate.Equal(want.OpenDate) {
+				t.Errorf("dates = %v %v, want %v %v", got.Periode, got.OpenDate, want.Periode, want.OpenDate)
+			}
+			if got.Balance != want.Balance || got.AvalBalance != want.AvalBalance {
+				t.Errorf("balances = %s %s, want %s %s", got.Balance, got.AvalBalance, want.Balance, want.AvalBalance)
+			}
+			if (got.AvgBalance == nil) != (want.AvgBalance == nil) || (got.AvgBalance != nil && *got.AvgBalance != *want.AvgBalance) {
+				t.Errorf("avg_balance = %v, want %v", got.AvgBalance, want.AvgBalance)
+			}
+			if (got.DropAmount == nil) != (want.DropAmount == nil) ||
+				(got.DropAmount != nil && (*got.DropAmount != *want.DropAmount || *got.DropPct != *want.DropPct)) {
+				t.Errorf("drop = %v %v, want %v %v", got.DropAmount, got.DropPct, want.DropAmount, want.DropPct)
+			}
+			if got.QualifyingRule != want.QualifyingRule {
+				t.Errorf("qualifying_rule = %q, want %q", got.QualifyingRule, want.QualifyingRule)
+			}
+		})
+	}
+}
+
+func TestDI319StagingRowMalformed(t *testing.T) {
+	valid := strings.TrimSuffix(encodeDI319StagingRow(models.DI319{
+		Periode: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), NoRek: "1",
+	}), "\n")
+	fields := strings.Split(valid, "\t")
+	with := func(i int, v string) string {
+		f := append([]string(nil), fields...)
+		f[i] = v
+		return strings.Join(f, "\t")
+	}
+
+	for name, line := range map[string]string{
+		"too few columns": strings.Join(fields[1:], "\t"),
+		"bad periode":     with(0, "31/01/2025"),
+		"bad balance":     with(8, "abc"),
+		"bad avg_balance": with(10, "1,2,3.4.5"),
+		"bad open_date":   with(11, ""),
+	} {
+		if _, err := decodeDI319StagingRow(line); err == nil {
+			t.Errorf("%s: expected an error for %q", name, line)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"fmt"
+	"pipeline-backend/models"
+	"pipeline-backend/money"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultDI319ProfileName - Profile seeded at startup from the built-in layout
+const DefaultDI319ProfileName = "default"
+
+// di319FieldAliases - Built-in header names for each DI319 field, in lookup order
+var di319FieldAliases = map[string][]string{
+	"periode":      {"periode", "textbox16", "date"},
+	"main_branch":  {"main_branch", "textbox22", "mainbranch"},
+	"branch":       {"branch", "textbox8", "kode_uker"},
+	"cif":          {"cif", "cifno", "customer_id"},
+	"norek":        {"norek", "textbox15", "account_no"},
+	"type":         {"type", "sccode", "product_type"},
+	"nama":         {"nama", "textbox38", "name", "customer_name"},
+	"pn_pengelola": {"pn_pengelola", "pn_rm_dana", "pn_singlepn", "pn_rm_pinjaman", "pn_relationship_officer"},
+	"balance":      {"balance", "saldo", "current_balance"},
+	"aval_balance": {"aval_balance", "availbalance", "available_balance"},
+	"avg_balance":  {"avg_balance", "avrgbalance", "average_balance"},
+	"open_date":    {"open_date", "textbox2", "opening_date"},
+}
+
+// di319DateFormats - Built-in date formats per date field
+var di319DateFormats = map[string][]string{
+	"periode":   {"dd/MM/yyyy", "yyyy-MM-dd"},
+	"open_date": {"M/d/yyyy", "yyyy-MM-dd", "dd/MM/yyyy"},
+}
+
+// di319FieldOrder - Logical DI319 fields in column order
+var di319FieldOrder = []string{
+	"periode", "main_branch", "branch", "cif", "norek", "type", "nama",
+	"pn_pengelola", "balance", "aval_balance", "avg_balance", "open_date",
+}
+
+// di319RequiredFields - Fields a row cannot be imported without
+var di319RequiredFields = []string{"periode", "branch", "cif", "norek", "balance"}
+
+// DefaultDI319Profile - The built-in report layout as an import profile
+func DefaultDI319Profile() models.ImportProfile {
+	return models.ImportProfile{
+		Name:              DefaultDI319ProfileName,
+		Description:       "Built-in DI319 layout (SSRS textbox columns and plain column names)",
+		Aliases:           di319FieldAliases,
+		DateFormats:       di319DateFormats,
+		ThousandSeparator: ",",
+		DecimalSeparator:  ".",
+		IsActive:          true,
+	}
+}
+
+// loadDI319Profiles - The chosen profile (by name or ID), or every active profile for auto-detection
+func loadDI319Profiles(db *gorm.DB, chosen string) ([]*models.ImportProfile, error) {
+	var profiles []*models.ImportProfile
+
+	if chosen != "" {
+		var profile models.ImportProfile
+		query := db.Where("name = ?", chosen)
+		if id, err := strconv.Atoi(chosen); err == nil {
+			query = db.Where("id = ?", id)
+		}
+		if err := query.First(&profile).Error; err != nil {
+			return nil, fmt.Errorf("Import profile %q not found", chosen)
+		}
+		return []*models.ImportProfile{&profile}, nil
+	}
+
+	if err := db.Where("is_active = ?", true).Order("id ASC").Find(&profiles).Error; err != nil {
+		return nil, fmt.Errorf("Failed to load import profiles")
+	}
+	if len(profiles) == 0 {
+		profile := DefaultDI319Profile()
+		profiles = append(profiles, &profile)
+	}
+	return profiles, nil
+}
+
+// validateDI319Profile - Check a profile submitted by an admin
+func validateDI319Profile(p *models.ImportProfile) error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	known := make(map[string]bool, len(di319FieldOrder))
+	for _, field := range di319FieldOrder {
+		known[field] = true
+	}
+	for field, aliases := range p.Aliases {
+		if !known[field] {
+			return fmt.Errorf("unknown field %q in aliases", field)
+		}
+		for i, alias := range aliases {
+			aliases[i] = normalizeHeaderCell(alias)
+		}
+	}
+	for _, field := range di319RequiredFields {
+		if len(p.Aliases[field]) == 0 {
+			return fmt.Errorf("aliases for required field %q are missing", field)
+		}
+	}
+
+	for field, formats := range p.DateFormats {
+		if field != "periode" && field != "open_date" {
+			return fmt.Errorf("date_formats only apply to periode and open_date, got %q", field)
+		}
+		if len(formats) == 0 {
+			return fmt.Errorf("date_formats for %q is empty", field)
+		}
+	}
+
+	if p.DecimalSeparator == "" {
+		p.DecimalSeparator = "."
+	}
+	if p.DecimalSeparator != "." && p.DecimalSeparator != "," {
+		return fmt.Errorf("decimal_separator must be \".\" or \",\"")
+	}
+	if p.ThousandSeparator != "" && !strings.Contains(".,' ", p.ThousandSeparator) {
+		return fmt.Errorf("thousand_separator must be one of . , ' or space")
+	}
+	if p.ThousandSeparator == p.DecimalSeparator {
+		return fmt.Errorf("thousand_separator and decimal_separator must differ")
+	}
+
+	return nil
+}
+
+// di319Mapping - A profile resolved against one sheet's header
+type di319Mapping struct {
+	Profile   *models.ImportProfile
+	headerMap map[string]int
+}
+
+func newDI319Mapping(profile *models.ImportProfile, header []string) *di319Mapping {
+	headerMap := make(map[string]int)
+	for i, col := range header {
+		headerMap[normalizeHeaderCell(col)] = i
+	}
+	return &di319Mapping{Profile: profile, headerMap: headerMap}
+}
+
+// Field - Value of a logical field, trying the profile's aliases in order
+func (m *di319Mapping) Field(record []string, field string) string {
+	for _, name := range m.Profile.Aliases[field] {
+		if idx, ok := m.headerMap[strings.ToLower(name)]; ok && idx < len(record) {
+			return strings.TrimSpace(record[idx])
+		}
+	}
+	return ""
+}
+
+// ParseDate - Parse a date field with the profile's formats (built-in formats when none are set)
+func (m *di319Mapping) ParseDate(field, value string) (time.Time, error) {
+	formats := m.Profile.DateFormats[field]
+	if len(formats) == 0 {
+		formats = di319DateFormats[field]
+	}
+	for _, format := range formats {
+		if t, err := time.Parse(toGoDateLayout(format), value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid %s format: %s", field, value)
+}
+
+// ParseAmount - Parse a money field. Both 1,234,567.89 and 1.234.567,89 are accepted whatever the
+// profile says; the profile only settles a lone separator before three digits, which is read as the
+// profile's decimal separator (1234.500 is 1234.50 under a '.' profile).
+func (m *di319Mapping) ParseAmount(value string) (money.Amount, error) {
+	var hint rune
+	if m.Profile.DecimalSeparator != "" && m.Profile.DecimalSeparator != m.Profile.ThousandSeparator {
+		hint = rune(m.Profile.DecimalSeparator[0])
+	}
+	return money.ParseHint(value, hint)
+}
+
+// matchDI319Header - Logical fields a header row provides under a profile, in di319FieldOrder order
+func matchDI319Header(header []string, profile *models.ImportProfile) []string {
+	cells := make(map[string]bool, len(header))
+	for _, cell := range header {
+		cells[normalizeHeaderCell(cell)] = true
+	}
+
+	matched := []string{}
+	for _, field := range di319FieldOrder {
+		for _, alias := range profile.Aliases[field] {
+			if cells[strings.ToLower(alias)] {
+				matched = append(matched, field)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// bestDI319Profile - Profile matching the most fields of a header row (earliest profile wins ties)
+func bestDI319Profile(header []string, profiles []*models.ImportProfile) (*models.ImportProfile, []string) {
+	var best *models.ImportProfile
+	var bestMatched []string
+	for _, profile := range profiles {
+		matched := matchDI319Header(header, profile)
+		if best == nil || len(matched) > len(bestMatched) {
+			best, bestMatched = profile, matched
+		}
+	}
+	return best, bestMatched
+}
+
+// toGoDateLayout - Convert dd/MM/yyyy style formats to Go layouts (Go layouts pass through)
+func toGoDateLayout(format string) string {
+	if strings.Contains(format, "2006") {
+		return format
+	}
+	replacer := strings.NewReplacer(
+		"yyyy", "2006", "yy", "06",
+		"MMMM", "January", "MMM", "Jan", "MM", "01", "M", "1",
+		"dd", "02", "d", "2",
+	)
+	return replacer.Replace(format)
+}
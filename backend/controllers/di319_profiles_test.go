@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"pipeline-backend/models"
+	"pipeline-backend/money"
+	"testing"
+)
+
+func TestDI319MappingParseAmount(t *testing.T) {
+	dotDecimal := DefaultDI319Profile()
+	commaDecimal := models.ImportProfile{ThousandSeparator: ".", DecimalSeparator: ","}
+	noGrouping := models.ImportProfile{DecimalSeparator: "."}
+
+	tests := []struct {
+		name    string
+		profile *models.ImportProfile
+		in      string
+		want    money.Amount
+	}{
+		{"dot profile, lone dot before three digits", &dotDecimal, "1234.500", 123450},
+		{"dot profile, indonesian grouping", &dotDecimal, "1.234.567,89", 123456789},
+		{"dot profile, lone comma is grouping", &dotDecimal, "1,234", 123400},
+		{"comma profile, lone comma before three digits", &commaDecimal, "1234,500", 123450},
+		{"comma profile, lone dot is grouping", &commaDecimal, "1.234", 123400},
+		{"no grouping profile", &noGrouping, "1.234", 123},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newDI319Mapping(tt.profile, nil)
+			got, err := m.ParseAmount(tt.in)
+			if err != nil {
+				t.Fatalf("ParseAmount(%q): %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAmount(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"pipeline-backend/models"
+	"pipeline-backend/money"
+	"pipeline-backend/pagination"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// di319SnapshotPartition - Partition name for a periode's month, e.g. p202610
+func di319SnapshotPartition(periode time.Time) string {
+	return periode.Format("p200601")
+}
+
+// prepareDI319SnapshotPeriode - Make sure the periode's month has its own partition and clear any
+// rows a previous import left for the same periode in both di319 and the snapshot, so re-importing
+// an extract replaces it instead of duplicating its candidates
+func (c *DI319ImportController) prepareDI319SnapshotPeriode(periode time.Time) error {
+	if err := c.ensureDI319SnapshotPartition(periode); err != nil {
+		return fmt.Errorf("partition %s: %v", di319SnapshotPartition(periode), err)
+	}
+
+	day := periode.Format("2006-01-02")
+	var snapshotRows, candidateRows int64
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("periode = ?", day).Delete(&models.DI319Snapshot{})
+		if result.Error != nil {
+			return result.Error
+		}
+		snapshotRows = result.RowsAffected
+
+		result = tx.Where("periode = ?", day).Delete(&models.DI319{})
+		if result.Error != nil {
+			return result.Error
+		}
+		candidateRows = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if snapshotRows > 0 || candidateRows > 0 {
+		log.Printf("🔁 Replacing %d snapshot rows and %d candidates for periode %s", snapshotRows, candidateRows, day)
+	}
+	return nil
+}
+
+// di319SnapshotPartitionInfo - One partition of di319_snapshot as listed by information_schema
+type di319SnapshotPartitionInfo struct {
+	Name  string
+	Bound string // quoted upper bound, e.g. '2026-11-01', or MAXVALUE
+}
+
+// ensureDI319SnapshotPartition - Give the periode's month its own partition, splitting it off whichever
+// partition currently holds it (the catch-all for new months, an older range for back-filled ones)
+func (c *DI319ImportController) ensureDI319SnapshotPartition(periode time.Time) error {
+	var partitions []di319SnapshotPartitionInfo
+	err := c.DB.Raw(`SELECT PARTITION_NAME AS name, PARTITION_DESCRIPTION AS bound
+		FROM information_schema.PARTITIONS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'di319_snapshot' AND PARTITION_NAME IS NOT NULL
+		ORDER BY PARTITION_ORDINAL_POSITION`).
+		Scan(&partitions).Error
+	if err != nil {
+		return err
+	}
+
+	statement, err := di319SnapshotReorganizeSQL(partitions, periode)
+	if err != nil || statement == "" {
+		return err
+	}
+	return c.DB.Exec(statement).Error
+}
+
+// di319SnapshotReorganizeSQL - The ALTER TABLE that splits the periode's month out of the partition
+// containing it, keeping the months below and above in partitions of their own. Empty when the month
+// already has its partition.
+func di319SnapshotReorganizeSQL(partitions []di319SnapshotPartitionInfo, periode time.Time) (string, error) {
+	monthStart := time.Date(periode.Year(), periode.Month(), 1, 0, 0, 0, 0, time.UTC)
+	nextMonth := monthStart.AddDate(0, 1, 0)
+	name := di319SnapshotPartition(monthStart)
+
+	var err error
+	var lower time.Time // upper bound of the partition before the containing one, zero for the first
+	for i, p := range partitions {
+		maxValue := p.Bound == "MAXVALUE"
+		var bound time.Time
+		if !maxValue {
+			// PARTITION_DESCRIPTION holds the quoted upper bound, e.g. '2026-11-01'
+			if bound, err = time.Parse("'2006-01-02'", p.Bound); err != nil {
+				return "", fmt.Errorf("partition %s has an unexpected bound %s", p.Name, p.Bound)
+			}
+			if !bound.After(monthStart) {
+				lower = bound
+				continue
+			}
+			if i > 0 && lower.Equal(monthStart) && bound.Equal(nextMonth) {
+				return "", nil
+			}
+		}
+
+		var parts []string
+		if i == 0 || lower.Before(monthStart) {
+			parts = append(parts, fmt.Sprintf("PARTITION %s VALUES LESS THAN ('%s')",
+				di319SnapshotPartition(monthStart.AddDate(0, -1, 0)), monthStart.Format("2006-01-02")))
+		}
+		parts = append(parts, fmt.Sprintf("PARTITION %s VALUES LESS THAN ('%s')", name, nextMonth.Format("2006-01-02")))
+		if maxValue {
+			parts = append(parts, fmt.Sprintf("PARTITION %s VALUES LESS THAN (MAXVALUE)", p.Name))
+		} else if bound.After(nextMonth) {
+			parts = append(parts, fmt.Sprintf("PARTITION %s VALUES LESS THAN (%s)", p.Name, p.Bound))
+		}
+		return fmt.Sprintf("ALTER TABLE di319_snapshot REORGANIZE PARTITION %s INTO (%s)",
+			p.Name, strings.Join(parts, ", ")), nil
+	}
+	return "", fmt.Errorf("no partition holds %s", monthStart.Format("2006-01"))
+}
+
+// toDI319Snapshot - Copy a parsed record into a snapshot row (IDs are assigned per table)
+func toDI319Snapshot(record models.DI319) models.DI319Snapshot {
+	record.ID = 0
+	return models.DI319Snapshot{DI319: record}
+}
+
+// di319Penetration - Share of a book that qualified as drop candidates in one snapshot periode
+type di319Penetration struct {
+	Name               string       `json:"name,omitempty"`
+	Accounts           int64        `json:"accounts"`
+	Candidates         int64        `json:"candidates"`
+	AccountPenetration float64      `json:"account_penetration"` // candidates / accounts, in percent
+	BookAvgBalance     money.Amount `json:"book_avg_balance"`
+	DroppedAvgBalance  money.Amount `json:"dropped_avg_balance"`
+	BalancePenetration float64      `json:"balance_penetration"` // dropped / book average balance, in percent
+}
+
+const di319PenetrationColumns = `COUNT(*) AS accounts,
+	COALESCE(SUM(qualifying_rule <> ''), 0) AS candidates,
+	COALESCE(SUM(avg_balance), 0) AS book_avg_balance,
+	COALESCE(SUM(CASE WHEN qualifying_rule <> '' THEN avg_balance END), 0) AS dropped_avg_balance`
+
+func (p *di319Penetration) computeRates() {
+	if p.Accounts > 0 {
+		p.AccountPenetration = math.Round(float64(p.Candidates)/float64(p.Accounts)*10000) / 100
+	}
+	if p.BookAvgBalance > 0 {
+		p.BalancePenetration = math.Round(p.DroppedAvgBalance.Float()/p.BookAvgBalance.Float()*10000) / 100
+	}
+}
+
+// snapshotPenetration - Overall and per-branch penetration for a periode (the latest snapshot when empty),
+// limited to scope when given. Returns nil when no snapshot has been imported.
+func (c *DI319ImportController) snapshotPenetration(periode string, scope *pagination.Filter) (fiber.Map, error) {
+	if scope == nil {
+		scope = &pagination.Filter{}
+	}
+	if periode == "" {
+		var latest sql.NullTime
+		if err := c.DB.Raw("SELECT MAX(periode) FROM di319_snapshot").Row().Scan(&latest); err != nil {
+			return nil, err
+		}
+		if !latest.Valid {
+			return nil, nil
+		}
+		periode = latest.Time.Format("2006-01-02")
+	}
+
+	var overall di319Penetration
+	args := append([]interface{}{periode}, scope.Args...)
+	err := c.DB.Raw("SELECT "+di319PenetrationColumns+" FROM di319_snapshot WHERE periode = ? AND "+scope.SQL(), args...).
+		Scan(&overall).Error
+	if err != nil {
+		return nil, err
+	}
+	overall.computeRates()
+
+	var branches []di319Penetration
+	err = c.DB.Raw("SELECT branch AS name, "+di319PenetrationColumns+
+		" FROM di319_snapshot WHERE periode = ? AND "+scope.SQL()+" GROUP BY branch"+
+		" ORDER BY SUM(qualifying_rule <> '') / COUNT(*) DESC, accounts DESC LIMIT 10", args...).
+		Scan(&branches).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range branches {
+		branches[i].computeRates()
+	}
+
+	return fiber.Map{
+		"periode":  periode,
+		"overall":  overall,
+		"branches": branches,
+	}, nil
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDI319SnapshotReorganizeSQL(t *testing.T) {
+	feb := time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		partitions []di319SnapshotPartitionInfo
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "first import",
+			partitions: []di319SnapshotPartitionInfo{{"pmax", "MAXVALUE"}},
+			want: "ALTER TABLE di319_snapshot REORGANIZE PARTITION pmax INTO (PARTITION p202601 VALUES LESS THAN ('2026-02-01'), " +
+				"PARTITION p202602 VALUES LESS THAN ('2026-03-01'), PARTITION pmax VALUES LESS THAN (MAXVALUE))",
+		},
+		{
+			name:       "next month",
+			partitions: []di319SnapshotPartitionInfo{{"p202601", "'2026-02-01'"}, {"pmax", "MAXVALUE"}},
+			want: "ALTER TABLE di319_snapshot REORGANIZE PARTITION pmax INTO (PARTITION p202602 VALUES LESS THAN ('2026-03-01'), " +
+				"PARTITION pmax VALUES LESS THAN (MAXVALUE))",
+		},
+		{
+			name:       "already split",
+			partitions: []di319SnapshotPartitionInfo{{"p202601", "'2026-02-01'"}, {"p202602", "'2026-03-01'"}, {"pmax", "MAXVALUE"}},
+		},
+		{
+			name:       "back-filled into the middle of an older range",
+			partitions: []di319SnapshotPartitionInfo{{"p202512", "'2026-01-01'"}, {"p202603", "'2026-04-01'"}, {"pmax", "MAXVALUE"}},
+			want: "ALTER TABLE di319_snapshot REORGANIZE PARTITION p202603 INTO (PARTITION p202601 VALUES LESS THAN ('2026-02-01'), " +
+				"PARTITION p202602 VALUES LESS THAN ('2026-03-01'), PARTITION p202603 VALUES LESS THAN ('2026-04-01'))",
+		},
+		{
+			name:       "back-filled below the first partition",
+			partitions: []di319SnapshotPartitionInfo{{"p202603", "'2026-04-01'"}, {"pmax", "MAXVALUE"}},
+			want: "ALTER TABLE di319_snapshot REORGANIZE PARTITION p202603 INTO (PARTITION p202601 VALUES LESS THAN ('2026-02-01'), " +
+				"PARTITION p202602 VALUES LESS THAN ('2026-03-01'), PARTITION p202603 VALUES LESS THAN ('2026-04-01'))",
+		},
+		{
+			name:       "top month of an older range",
+			partitions: []di319SnapshotPartitionInfo{{"p202512", "'2026-01-01'"}, {"p202602", "'2026-03-01'"}, {"pmax", "MAXVALUE"}},
+			want: "ALTER TABLE di319_snapshot REORGANIZE PARTITION p202602 INTO (PARTITION p202601 VALUES LESS THAN ('2026-02-01'), " +
+				"PARTITION p202602 VALUES LESS THAN ('2026-03-01'))",
+		},
+		{
+			name:       "unreadable bound",
+			partitions: []di319SnapshotPartitionInfo{{"p1", "2026"}, {"pmax", "MAXVALUE"}},
+			wantErr:    true,
+		},
+		{
+			name:       "no catch-all",
+			partitions: []di319SnapshotPartitionInfo{{"p202601", "'2026-02-01'"}},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := di319SnapshotReorganizeSQL(tt.partitions, feb)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path"
+	"pipeline-backend/models"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// di319RecordReader - Row source for the DI319 importer (CSV, gzip CSV, zip entry, xlsx sheet)
+type di319RecordReader interface {
+	Read() ([]string, error)
+}
+
+// di319Sheet - One tabular source inside an upload, positioned just after its header
+type di319Sheet struct {
+	Name        string
+	Reader      di319RecordReader
+	Header      []string
+	HeaderIndex int
+	Dialect     di319Dialect
+	Mapping     *di319Mapping
+}
+
+// isSupportedDI319File - Extensions accepted by the DI319 importer
+func isSupportedDI319File(filename string) bool {
+	name := strings.ToLower(filename)
+	for _, ext := range []string{".csv", ".csv.gz", ".gz", ".zip", ".xlsx"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// openDI319Sheets - Unpack an upload into header-detected sheets. The returned closer releases
+// everything that was opened and must be called once the sheets have been consumed.
+func openDI319Sheets(source *importSource, sheetName string, profiles []*models.ImportProfile) ([]*di319Sheet, func(), error) {
+	file, err := source.Open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to open file")
+	}
+
+	closers := []io.Closer{file}
+	closeAll := func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i].Close()
+		}
+	}
+
+	var sheets []*di319Sheet
+	var sheet *di319Sheet
+	name := strings.ToLower(source.Filename)
+	switch {
+	case strings.HasSuffix(name, ".xlsx"):
+		sheet, err = openDI319XLSXSheet(file, sheetName, profiles, &closers)
+	case strings.HasSuffix(name, ".zip"):
+		sheets, err = openDI319ZipSheets(file, profiles, &closers)
+	case strings.HasSuffix(name, ".gz"):
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(file); err != nil {
+			err = fmt.Errorf("Invalid gzip file: %v", err)
+			break
+		}
+		closers = append(closers, gz)
+		sheet, err = sniffDI319CSV(source.Filename, gz, profiles)
+	default:
+		sheet, err = sniffDI319CSV(source.Filename, file, profiles)
+	}
+	if sheet != nil {
+		sheets = append(sheets, sheet)
+	}
+
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	if len(sheets) == 0 {
+		closeAll()
+		return nil, nil, fmt.Errorf("No CSV data found in file")
+	}
+
+	return sheets, closeAll, nil
+}
+
+// openDI319ZipSheets - Every .csv (or .csv.gz) entry in the archive, in name order
+func openDI319ZipSheets(file multipart.File, profiles []*models.ImportProfile, closers *[]io.Closer) ([]*di319Sheet, error) {
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read zip file")
+	}
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid zip file: %v", err)
+	}
+
+	entries := make([]*zip.File, 0, len(archive.File))
+	for _, entry := range archive.File {
+		entryName := strings.ToLower(entry.Name)
+		if entry.FileInfo().IsDir() || strings.HasPrefix(path.Base(entryName), ".") {
+			continue
+		}
+		if strings.HasSuffix(entryName, ".csv") || strings.HasSuffix(entryName, ".csv.gz") {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	var sheets []*di319Sheet
+	for _, entry := range entries {
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", entry.Name, err)
+		}
+		*closers = append(*closers, rc)
+
+		var entryReader io.Reader = rc
+		if strings.HasSuffix(strings.ToLower(entry.Name), ".gz") {
+			gz, err := gzip.NewReader(rc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", entry.Name, err)
+			}
+			*closers = append(*closers, gz)
+			entryReader = gz
+		}
+
+		sheet, err := sniffDI319CSV(entry.Name, entryReader, profiles)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", entry.Name, err)
+		}
+		sheets = append(sheets, sheet)
+	}
+
+	return sheets, nil
+}
+
+// openDI319XLSXSheet - Stream rows from the chosen sheet (or the first one)
+func openDI319XLSXSheet(file multipart.File, sheetName string, profiles []*models.ImportProfile, closers *[]io.Closer) (*di319Sheet, error) {
+	workbook, err := excelize.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid xlsx file: %v", err)
+	}
+	*closers = append(*closers, workbook)
+
+	if sheetName == "" {
+		sheetName = workbook.GetSheetName(0)
+	} else if idx, err := workbook.GetSheetIndex(sheetName); err != nil || idx < 0 {
+		return nil, fmt.Errorf("Sheet %q not found", sheetName)
+	}
+
+	// Score the leading rows like the CSV sniffer does, then re-open the iterator at the header
+	rows, err := workbook.Rows(sheetName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read sheet %q: %v", sheetName, err)
+	}
+	bestRow, bestScore := -1, 0
+	var header, matched []string
+	var profile *models.ImportProfile
+	for i := 0; i < di319HeaderScanLines && rows.Next(); i++ {
+		line, err := rows.Columns()
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("Failed to read sheet %q: %v", sheetName, err)
+		}
+		if p, m := bestDI319Profile(line, profiles); len(m) > bestScore {
+			bestRow, bestScore, header, matched, profile = i, len(m), line, m, p
+		}
+	}
+	rows.Close()
+	if bestRow < 0 || bestScore < di319MinHeaderScore {
+		return nil, fmt.Errorf("Failed to find header in sheet %q", sheetName)
+	}
+
+	rows, err = workbook.Rows(sheetName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read sheet %q: %v", sheetName, err)
+	}
+	*closers = append(*closers, rows)
+	for i := 0; i <= bestRow; i++ {
+		rows.Next()
+	}
+
+	return &di319Sheet{
+		Name:        sheetName,
+		Reader:      &xlsxRowReader{rows: rows},
+		Header:      header,
+		HeaderIndex: bestRow,
+		Dialect: di319Dialect{
+			Source:         sheetName,
+			Format:         "xlsx",
+			HeaderRow:      bestRow + 1,
+			HeaderScore:    bestScore,
+			Profile:        profile.Name,
+			Columns:        len(header),
+			MatchedFields:  matched,
+			MissingFields:  missingDI319Fields(matched),
+			SkippedPrelude: bestRow,
+		},
+		Mapping: newDI319Mapping(profile, header),
+	}, nil
+}
+
+// xlsxRowReader - Adapts excelize's streaming row iterator to di319RecordReader
+type xlsxRowReader struct {
+	rows *excelize.Rows
+}
+
+func (r *xlsxRowReader) Read() ([]string, error) {
+	if !r.rows.Next() {
+		if err := r.rows.Error(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return r.rows.Columns()
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"pipeline-backend/models"
+	"testing"
+	"time"
+)
+
+const testDI319Header = "periode,main_branch,branch,cif,norek,type,nama,pn_pengelola,balance,aval_balance,avg_balance,open_date\n"
+
+// testDI319CSV - n rows; every other row dropped 60% against its average balance
+func testDI319CSV(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString(testDI319Header)
+	for i := 0; i < n; i++ {
+		balance := "1000.00"
+		if i%2 == 0 {
+			balance = "400.00"
+		}
+		fmt.Fprintf(&b, "31/01/2025,KC JAKARTA,00%03d,CIF%07d,%015d,SA,NASABAH %d,PN%06d,%s,%s,1000.00,1/2/2020\n",
+			i%100, i, i, i, i%50, balance, balance)
+	}
+	return b.Bytes()
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func fileSource(t *testing.T, name string, data []byte) *importSource {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return &importSource{Filename: name, Open: func() (multipart.File, error) { return os.Open(path) }}
+}
+
+// testDI319Profiles - The built-in layout, as auto-detection sees it without stored profiles
+func testDI319Profiles() []*models.ImportProfile {
+	profile := DefaultDI319Profile()
+	return []*models.ImportProfile{&profile}
+}
+
+// runTestDI319Parser - Drain the parse stages, failing the test if they never finish
+func runTestDI319Parser(t *testing.T, sheets []*di319Sheet) (records int, readErr error) {
+	t.Helper()
+	errs := make(chan error, 1)
+	c := &DI319ImportController{}
+	batches := c.startDI319Parser(sheets, di319ImportOptions{Workers: 2, BatchSize: 500}, &di319PeriodeSet{}, errs)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for batch := range batches {
+			records += len(batch.records)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("parser did not stop")
+	}
+	select {
+	case readErr = <-errs:
+	default:
+	}
+	return records, readErr
+}
+
+func TestDI319GzipSources(t *testing.T) {
+	const rows = 20000 // well past the sniffer's sample, so truncation hits the row reader
+	full := gzipBytes(t, testDI319CSV(rows))
+
+	tests := []struct {
+		name        string
+		data        []byte
+		wantRecords int
+		wantErr     bool
+	}{
+		{"complete", full, rows / 2, false},
+		{"truncated", full[:len(full)*3/4], -1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheets, closeAll, err := openDI319Sheets(fileSource(t, "di319.csv.gz", tt.data), "", testDI319Profiles())
+			if err != nil {
+				t.Fatalf("openDI319Sheets: %v", err)
+			}
+			defer closeAll()
+
+			records, readErr := runTestDI319Parser(t, sheets)
+			if (readErr != nil) != tt.wantErr {
+				t.Fatalf("read error = %v, want error %v", readErr, tt.wantErr)
+			}
+			if tt.wantRecords >= 0 && records != tt.wantRecords {
+				t.Errorf("records = %d, want %d", records, tt.wantRecords)
+			}
+		})
+	}
+}
+
+func TestDI319CorruptGzipHeader(t *testing.T) {
+	_, _, err := openDI319Sheets(fileSource(t, "di319.csv.gz", []byte("not gzip at all")), "", testDI319Profiles())
+	if err == nil {
+		t.Fatal("expected an invalid gzip error")
+	}
+}
+
+// scriptedReader - Replays fixed Read results, then io.EOF
+type scriptedReader struct {
+	results []scriptedRead
+}
+
+type scriptedRead struct {
+	record []string
+	err    error
+}
+
+func (r *scriptedReader) Read() ([]string, error) {
+	if len(r.results) == 0 {
+		return nil, io.EOF
+	}
+	next := r.results[0]
+	r.results = r.results[1:]
+	return next.record, next.err
+}
+
+func TestDI319ReaderErrors(t *testing.T) {
+	profile := DefaultDI319Profile()
+	header := []string{"periode", "branch", "cif", "norek", "balance", "avg_balance"}
+	row := []string{"31/01/2025", "00001", "CIF1", "1", "100.00", "1000.00"}
+
+	tests := []struct {
+		name        string
+		reads       []scriptedRead
+		wantRecords int
+		wantErr     bool
+	}{
+		{
+			name:        "parse errors are skipped",
+			reads:       []scriptedRead{{row, nil}, {nil, &csv.ParseError{Line: 3, Err: csv.ErrFieldCount}}, {row, nil}},
+			wantRecords: 2,
+		},
+		{
+			name:        "other errors stop the read",
+			reads:       []scriptedRead{{row, nil}, {nil, io.ErrUnexpectedEOF}, {row, nil}},
+			wantRecords: 1,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheet := &di319Sheet{
+				Name:    "scripted",
+				Reader:  &scriptedReader{results: tt.reads},
+				Header:  header,
+				Mapping: newDI319Mapping(&profile, header),
+			}
+			records, readErr := runTestDI319Parser(t, []*di319Sheet{sheet})
+			if (readErr != nil) != tt.wantErr {
+				t.Fatalf("read error = %v, want error %v", readErr, tt.wantErr)
+			}
+			if records != tt.wantRecords {
+				t.Errorf("records = %d, want %d", records, tt.wantRecords)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package controllers
+
+import (
+	"fmt"
+	"pipeline-backend/money"
+	"pipeline-backend/pagination"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// di319StatsDimension - One way to group DI319 rows: the group key, a display label and the joins it needs.
+// Queries select from the filtered rows as d, so filter columns never clash with joined tables.
+type di319StatsDimension struct {
+	Key   string
+	Label string // "" when the key is its own label
+	Joins string
+	Desc  bool // natural order when sorting by name
+}
+
+// di319UkerJoin - Ukers deduplicated by code (so a repeated kode_uker cannot multiply rows), joined as alias on column
+func di319UkerJoin(alias, column string) string {
+	return fmt.Sprintf(` LEFT JOIN (SELECT kode_uker, MAX(nama_uker) AS nama_uker, MAX(region) AS region
+	FROM uker GROUP BY kode_uker) %[1]s ON %[1]s.kode_uker = %[2]s`, alias, column)
+}
+
+// di319StatsDimensions - Every dimension has its own join aliases, so dimensions can be combined in one query
+// (dimensions sharing a join use the identical clause, which is added once)
+var di319StatsDimensions = map[string]di319StatsDimension{
+	"branch": {
+		Key:   "d.branch",
+		Label: "MAX(ub.nama_uker)",
+		Joins: di319UkerJoin("ub", "d.branch"),
+	},
+	"main_branch": {
+		Key:   "d.main_branch",
+		Label: "MAX(um.nama_uker)",
+		Joins: di319UkerJoin("um", "d.main_branch"),
+	},
+	"region": {
+		Key:   "COALESCE(ub.region, '')",
+		Joins: di319UkerJoin("ub", "d.branch"),
+	},
+	"type": {
+		Key:   "d.type",
+		Label: "MAX(pt.nama_product)",
+		Joins: " LEFT JOIN product_type pt ON pt.kode_product = d.type",
+	},
+	"pn_pengelola": {
+		Key:   "d.pn_pengelola",
+		Label: "MAX(rf.nama_lengkap)",
+		// DI319 writes "PN123456", RFMT may store "123456"; officers are keyed by the DI319 form
+		Joins: ` LEFT JOIN (SELECT CONCAT('PN', TRIM(LEADING 'PN' FROM UPPER(pn))) AS pn, MAX(nama_lengkap) AS nama_lengkap
+			FROM rfmts WHERE deleted_at IS NULL GROUP BY 1) rf ON rf.pn = d.pn_pengelola`,
+	},
+	"periode": {
+		Key:  "DATE_FORMAT(d.periode, '%Y-%m-%d')",
+		Desc: true,
+	},
+}
+
+// di319StatsDimensionOrder - Dimensions returned when group_by is not given
+var di319StatsDimensionOrder = []string{"branch", "main_branch", "region", "type", "pn_pengelola", "periode"}
+
+// di319StatsSorts - Whitelisted sort keys for the groups
+var di319StatsSorts = map[string]string{
+	"name":              "name",
+	"accounts":          "accounts",
+	"total_balance":     "total_balance",
+	"total_avg_balance": "total_avg_balance",
+	"drop_count":        "drop_count",
+	"total_drop_amount": "total_drop_amount",
+}
+
+const di319StatsColumns = `COUNT(*) AS accounts,
+	COALESCE(SUM(d.balance), 0) AS total_balance,
+	COALESCE(SUM(d.aval_balance), 0) AS total_aval_balance,
+	COALESCE(SUM(d.avg_balance), 0) AS total_avg_balance,
+	COALESCE(SUM(d.qualifying_rule <> ''), 0) AS drop_count,
+	COALESCE(SUM(CASE WHEN d.drop_amount > 0 THEN d.drop_amount END), 0) AS total_drop_amount,
+	ROUND(AVG(d.drop_pct), 2) AS avg_drop_pct`
+
+// di319SummaryStatsColumns - di319StatsColumns over di319_summary
+const di319SummaryStatsColumns = `COALESCE(SUM(d.row_count), 0) AS accounts,
+	COALESCE(SUM(d.sum_balance), 0) AS total_balance,
+	COALESCE(SUM(d.sum_aval_balance), 0) AS total_aval_balance,
+	COALESCE(SUM(d.sum_avg_balance), 0) AS total_avg_balance,
+	COALESCE(SUM(d.drop_count), 0) AS drop_count,
+	COALESCE(SUM(d.sum_drop), 0) AS total_drop_amount,
+	ROUND(SUM(d.sum_drop_pct) / NULLIF(SUM(d.drop_pct_count), 0), 2) AS avg_drop_pct`
+
+// di319StatsGroup - Aggregates over one group (or over every filtered row for the totals)
+type di319StatsGroup struct {
+	Name             string       `json:"name,omitempty"`
+	Label            string       `json:"label,omitempty"`
+	Accounts         int64        `json:"accounts"`
+	TotalBalance     money.Amount `json:"total_balance"`
+	TotalAvalBalance money.Amount `json:"total_aval_balance"`
+	TotalAvgBalance  money.Amount `json:"total_avg_balance"`
+	DropCount        int64        `json:"drop_count"`        // rows with a qualifying rule
+	TotalDropAmount  money.Amount `json:"total_drop_amount"` // sum of positive drops
+	AvgDropPct       *float64     `json:"avg_drop_pct"`      // nil when no row has an average balance
+}
+
+// di319StatsRequest - Parsed stats parameters
+type di319StatsRequest struct {
+	Filter      *pagination.Filter
+	Dimensions  []string
+	Sort        string
+	Order       string
+	Limit       int
+	FromSummary bool
+	Penetration bool               // include snapshot penetration rates
+	Periode     string             // penetration periode ("" for the latest)
+	Scope       *pagination.Filter // data scope of the penetration rates (nil for everything)
+}
+
+// di319Stats - Totals and per-dimension groups
+type di319Stats struct {
+	FromSummary bool                         `json:"from_summary"`
+	Totals      di319StatsGroup              `json:"totals"`
+	Dimensions  map[string][]di319StatsGroup `json:"dimensions"`
+	Penetration fiber.Map                    `json:"penetration"`
+}
+
+// parseDI319StatsRequest - Read the stats parameters, with the dimensions and group limit used when absent
+func parseDI319StatsRequest(ctx *fiber.Ctx, defaultDimensions []string, defaultLimit int) (*di319StatsRequest, error) {
+	filter, err := parseDI319Filters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r := &di319StatsRequest{
+		Filter:      filter,
+		Dimensions:  defaultDimensions,
+		Sort:        ctx.Query("sort"),
+		Order:       strings.ToLower(ctx.Query("order")),
+		Limit:       defaultLimit,
+		FromSummary: useDI319Summary(ctx),
+		Periode:     ctx.Query("periode"),
+	}
+
+	if groupBy := pagination.SplitList(ctx.Query("group_by")); len(groupBy) > 0 {
+		for _, name := range groupBy {
+			if _, ok := di319StatsDimensions[name]; !ok {
+				return nil, fmt.Errorf("invalid group_by: %s", name)
+			}
+		}
+		r.Dimensions = groupBy
+	}
+	if r.Sort != "" {
+		if _, ok := di319StatsSorts[r.Sort]; !ok {
+			return nil, fmt.Errorf("invalid sort: %s", r.Sort)
+		}
+	}
+	if r.Order != "" && r.Order != "asc" && r.Order != "desc" {
+		return nil, fmt.Errorf("invalid order: %s", r.Order)
+	}
+	if raw := ctx.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit: %s", raw)
+		}
+		r.Limit = pagination.ClampLimit(limit)
+	}
+	return r, nil
+}
+
+// GetStats - Aggregates over DI319 (GET /api/stats), grouped by branch, main_branch, region, type,
+// pn_pengelola and periode. Accepts the DI319 list filters plus:
+//   - group_by=branch,type to pick dimensions (default all)
+//   - sort=<accounts|total_balance|total_avg_balance|drop_count|total_drop_amount|name>, order=asc|desc
+//   - limit: groups per dimension (default 10)
+//
+// Reads di319_summary unless a filter needs row-level columns (or ?summary=false).
+func (c *DI319ImportController) GetStats(ctx *fiber.Ctx) error {
+	r, err := parseDI319StatsRequest(ctx, di319StatsDimensionOrder, 10)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if r.Scope, err = di319ScopeFilter(c.DB, ctx); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load data scope",
+		})
+	}
+	r.Filter.Add(r.Scope.SQL(), r.Scope.Args...)
+	r.Penetration = true
+
+	stats, failures := c.computeDI319Stats(r)
+	if len(failures) > 0 {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":    "Failed to compute stats",
+			"failures": failures,
+		})
+	}
+	return ctx.JSON(stats)
+}
+
+// computeDI319Stats - Run the totals, every dimension and the penetration in parallel.
+// Returns one message per failed query; any failure means the stats are incomplete.
+func (c *DI319ImportController) computeDI319Stats(r *di319StatsRequest) (*di319Stats, []string) {
+	table, columns := "di319", di319StatsColumns
+	if r.FromSummary {
+		table, columns = "di319_summary", di319SummaryStatsColumns
+	}
+	from := " FROM (SELECT * FROM " + table + " WHERE " + r.Filter.SQL() + ") d"
+
+	stats := &di319Stats{
+		FromSummary: r.FromSummary,
+		Dimensions:  make(map[string][]di319StatsGroup, len(r.Dimensions)),
+	}
+
+	var mu sync.Mutex
+	var failures []string
+	fail := func(what string, err error) {
+		mu.Lock()
+		failures = append(failures, fmt.Sprintf("%s: %v", what, err))
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1 + len(r.Dimensions))
+
+	go func() {
+		defer wg.Done()
+		if err := c.DB.Raw("SELECT "+columns+from, r.Filter.Args...).Scan(&stats.Totals).Error; err != nil {
+			fail("totals", err)
+		}
+	}()
+
+	for _, name := range r.Dimensions {
+		name, dim := name, di319StatsDimensions[name]
+		go func() {
+			defer wg.Done()
+			var groups []di319StatsGroup
+			label := dim.Label
+			if label == "" {
+				label = "''"
+			}
+			query := fmt.Sprintf("SELECT %s AS name, %s AS label, %s%s%s GROUP BY %s ORDER BY %s LIMIT ?",
+				dim.Key, label, columns, from, dim.Joins, dim.Key, di319StatsOrder(dim, r.Sort, r.Order))
+			args := append(append([]interface{}{}, r.Filter.Args...), r.Limit)
+			if err := c.DB.Raw(query, args...).Scan(&groups).Error; err != nil {
+				fail(name, err)
+				return
+			}
+			if groups == nil {
+				groups = []di319StatsGroup{}
+			}
+			mu.Lock()
+			stats.Dimensions[name] = groups
+			mu.Unlock()
+		}()
+	}
+
+	// Penetration rates from the full snapshot (absent until a snapshot import ran)
+	if r.Penetration {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			penetration, err := c.snapshotPenetration(r.Periode, r.Scope)
+			if err != nil {
+				fail("penetration", err)
+				return
+			}
+			mu.Lock()
+			stats.Penetration = penetration
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	return stats, failures
+}
+
+// di319StatsOrder - ORDER BY for a dimension's groups; the key breaks ties so pages are stable
+func di319StatsOrder(dim di319StatsDimension, sortKey, order string) string {
+	if sortKey == "" {
+		if dim.Desc {
+			sortKey, order = "name", "desc"
+		} else {
+			sortKey = "total_balance"
+		}
+	}
+	if order == "" {
+		order = "desc"
+		if sortKey == "name" {
+			order = "asc"
+		}
+	}
+	if sortKey == "name" {
+		return "name " + strings.ToUpper(order)
+	}
+	return fmt.Sprintf("%s %s, name", di319StatsSorts[sortKey], strings.ToUpper(order))
+}
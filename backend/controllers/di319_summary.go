@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const di319SummaryInsert = `INSERT INTO di319_summary (periode, branch, main_branch, type, pn_pengelola,
+	row_count, sum_balance, sum_aval_balance, sum_avg_balance, drop_count, sum_drop, sum_drop_pct, drop_pct_count,
+	max_drop_pct, refreshed_at)
+	SELECT periode, branch, main_branch, type, pn_pengelola,
+		COUNT(*), COALESCE(SUM(balance), 0), COALESCE(SUM(aval_balance), 0), COALESCE(SUM(avg_balance), 0),
+		COALESCE(SUM(qualifying_rule <> ''), 0), COALESCE(SUM(CASE WHEN drop_amount > 0 THEN drop_amount END), 0),
+		COALESCE(SUM(drop_pct), 0), COUNT(drop_pct), MAX(drop_pct), NOW()
+	FROM di319`
+
+const di319SummaryGroupBy = " GROUP BY periode, branch, main_branch, type, pn_pengelola"
+
+// di319PeriodeSet - Periodes touched by an import, collected by the parser
+type di319PeriodeSet struct {
+	mu       sync.Mutex
+	periodes map[time.Time]bool
+}
+
+func (s *di319PeriodeSet) add(periode time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.periodes == nil {
+		s.periodes = make(map[time.Time]bool)
+	}
+	s.periodes[periode] = true
+}
+
+// list - The periodes, oldest first
+func (s *di319PeriodeSet) list() []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]time.Time, 0, len(s.periodes))
+	for periode := range s.periodes {
+		list = append(list, periode)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Before(list[j]) })
+	return list
+}
+
+// refreshDI319Summary - Rebuild the summary rows of the given periodes from di319, one transaction per periode
+func refreshDI319Summary(db *gorm.DB, periodes []time.Time) error {
+	for _, periode := range periodes {
+		day := periode.Format("2006-01-02")
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("DELETE FROM di319_summary WHERE periode = ?", day).Error; err != nil {
+				return err
+			}
+			return tx.Exec(di319SummaryInsert+" WHERE periode = ?"+di319SummaryGroupBy, day).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebuildDI319Summary - Rebuild the whole summary from di319
+func RebuildDI319Summary(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM di319_summary").Error; err != nil {
+			return err
+		}
+		return tx.Exec(di319SummaryInsert + di319SummaryGroupBy).Error
+	})
+}
+
+// refreshDI319SummaryAfterImport - Refresh the periodes an import touched. Failures are logged and
+// returned as a note for the import message; the raw data is already saved.
+func (c *DI319ImportController) refreshDI319SummaryAfterImport(touched *di319PeriodeSet) string {
+	periodes := touched.list()
+	if len(periodes) == 0 {
+		return ""
+	}
+	start := time.Now()
+	if err := refreshDI319Summary(c.DB, periodes); err != nil {
+		log.Printf("⚠️  Failed to refresh DI319 summary: %v", err)
+		return ", summary refresh failed (stats may be stale)"
+	}
+	log.Printf("📊 DI319 summary refreshed for %d periode(s) in %v", len(periodes), time.Since(start))
+	return ""
+}
+
+// di319SummaryFilterParams - DI319 filters that exist as summary columns
+var di319SummaryFilterParams = map[string]bool{
+	"branch":       true,
+	"main_branch":  true,
+	"type":         true,
+	"pn_pengelola": true,
+	"periode":      true,
+}
+
+// useDI319Summary - Whether a stats / analytics request over di319 can be answered from the summary:
+// only filters on summary columns (which keep their di319 names, so the parsed filter applies as is),
+// and not turned off with ?summary=false
+func useDI319Summary(ctx *fiber.Ctx) bool {
+	if ctx.Query("summary") == "false" || ctx.Query("rule") != "" || ctx.Query("search") != "" {
+		return false
+	}
+	for _, field := range di319FilterFields {
+		if di319SummaryFilterParams[field.Param] {
+			continue
+		}
+		for _, suffix := range []string{"", "_from", "_to", "_min", "_max"} {
+			if ctx.Query(field.Param+suffix) != "" {
+				return false
+			}
+		}
+	}
+	return true
+}
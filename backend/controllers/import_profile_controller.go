@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"pipeline-backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type ImportProfileController struct {
+	DB *gorm.DB
+}
+
+func NewImportProfileController(db *gorm.DB) *ImportProfileController {
+	return &ImportProfileController{DB: db}
+}
+
+// GetAll - Get all DI319 import profiles
+func (c *ImportProfileController) GetAll(ctx *fiber.Ctx) error {
+	var profiles []models.ImportProfile
+
+	if err := c.DB.Order("name ASC").Find(&profiles).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"data":   profiles,
+		"fields": di319FieldOrder,
+	})
+}
+
+// GetByID - Get import profile by ID
+func (c *ImportProfileController) GetByID(ctx *fiber.Ctx) error {
+	var profile models.ImportProfile
+
+	if err := c.DB.First(&profile, ctx.Params("id")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Import profile not found",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"data": profile,
+	})
+}
+
+// importProfileRequest - Create/update body; is_active is optional (new profiles default to active,
+// updates keep the current value when it is left out)
+type importProfileRequest struct {
+	models.ImportProfile
+	IsActive *bool `json:"is_active"`
+}
+
+// Create - Create new import profile (admin only)
+func (c *ImportProfileController) Create(ctx *fiber.Ctx) error {
+	var req importProfileRequest
+
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	profile := req.ImportProfile
+	profile.ID = 0
+	profile.IsActive = req.IsActive == nil || *req.IsActive
+
+	if err := validateDI319Profile(&profile); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Check if name already exists
+	var existing models.ImportProfile
+	if err := c.DB.Where("name = ?", profile.Name).First(&existing).Error; err == nil {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Import profile with this name already exists",
+		})
+	}
+
+	if err := c.DB.Create(&profile).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Import profile created successfully",
+		"data":    profile,
+	})
+}
+
+// Update - Update import profile (admin only)
+func (c *ImportProfileController) Update(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	var profile models.ImportProfile
+
+	if err := c.DB.First(&profile, id).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Import profile not found",
+		})
+	}
+
+	var req importProfileRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	updateData := req.ImportProfile
+
+	if err := validateDI319Profile(&updateData); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Check if new name already exists (except current record)
+	var existing models.ImportProfile
+	if err := c.DB.Where("name = ? AND id != ?", updateData.Name, id).First(&existing).Error; err == nil {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Import profile with this name already exists",
+		})
+	}
+
+	profile.Name = updateData.Name
+	profile.Description = updateData.Description
+	profile.Aliases = updateData.Aliases
+	profile.DateFormats = updateData.DateFormats
+	profile.ThousandSeparator = updateData.ThousandSeparator
+	profile.DecimalSeparator = updateData.DecimalSeparator
+	if req.IsActive != nil {
+		profile.IsActive = *req.IsActive
+	}
+
+	if err := c.DB.Save(&profile).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "Import profile updated successfully",
+		"data":    profile,
+	})
+}
+
+// Delete - Delete import profile (admin only)
+func (c *ImportProfileController) Delete(ctx *fiber.Ctx) error {
+	var profile models.ImportProfile
+
+	if err := c.DB.First(&profile, ctx.Params("id")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Import profile not found",
+		})
+	}
+
+	if err := c.DB.Delete(&profile).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "Import profile deleted successfully",
+	})
+}
@@ -0,0 +1,412 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"pipeline-backend/money"
+	"pipeline-backend/pagination"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type LeaderboardController struct {
+	DB *gorm.DB
+}
+
+func NewLeaderboardController(db *gorm.DB) *LeaderboardController {
+	return &LeaderboardController{DB: db}
+}
+
+// leaderboardFilterFields - Candidate filters accepted by the leaderboards
+var leaderboardFilterFields = []pagination.FilterField{
+	{Param: "branch", Column: "branch", Kind: pagination.FilterIn},
+	{Param: "main_branch", Column: "main_branch", Kind: pagination.FilterIn},
+	{Param: "type", Column: "type", Kind: pagination.FilterIn},
+}
+
+// leaderboardBoards - What can be ranked; rmft joins DI319.PNPengelola to RFMT.PN
+var leaderboardBoards = map[string]di319StatsDimension{
+	"uker":        di319StatsDimensions["branch"],
+	"main_branch": di319StatsDimensions["main_branch"],
+	"region":      di319StatsDimensions["region"],
+	"rmft": {
+		Key:   "d.pn_pengelola",
+		Label: "MAX(rf.nama_lengkap)",
+	},
+}
+
+var leaderboardBoardOrder = []string{"uker", "main_branch", "region", "rmft"}
+
+// leaderboardRecoverySorts - Ranking metrics that need a recovery periode to be measured
+var leaderboardRecoverySorts = map[string]bool{"recovered": true, "recovery_rate": true}
+
+// leaderboardSorts - Ranking metrics
+var leaderboardSorts = map[string]string{
+	"candidates":    "candidates",
+	"total_drop":    "total_drop",
+	"recovered":     "recovered",
+	"recovery_rate": "recovery_rate",
+}
+
+// leaderboardEntry - One ranked uker / main branch / region / officer
+type leaderboardEntry struct {
+	Rank            int           `json:"rank"`
+	Name            string        `json:"name"`
+	Label           string        `json:"label,omitempty"`
+	JG              string        `json:"jg,omitempty"`
+	KelompokJabatan string        `json:"kelompok_jabatan_rmft,omitempty"`
+	Candidates      int64         `json:"candidates"`
+	TotalDrop       money.Amount  `json:"total_drop"`
+	FollowedUp      int64         `json:"followed_up"`   // candidates found in the recovery periode
+	Recovered       *money.Amount `json:"recovered"`     // nil without a recovery periode
+	RecoveryRate    *float64      `json:"recovery_rate"` // recovered / total drop, in percent
+}
+
+// leaderboardQuery - Everything that defines one ranking
+type leaderboardQuery struct {
+	Periode         time.Time
+	RecoveryPeriode *time.Time
+	Filter          *pagination.Filter
+	JG              []string
+	KelompokJabatan []string
+	Sort            string
+	Desc            bool
+	Limit           int
+}
+
+// GetLeaderboards - Rank ukers, main branches, regions and RMFT officers for one periode
+// (GET /api/leaderboards). Recovery compares each candidate's balance in the recovery periode
+// (the next snapshot periode by default) with its balance at the drop, capped at the drop amount.
+// recovery_available is false when no such periode exists: recovered and recovery_rate are then null, not zero.
+//   - periode=yyyy-mm-dd (default the latest DI319 periode), recovery_periode=yyyy-mm-dd
+//   - by=uker,main_branch,region,rmft (default all)
+//   - sort=candidates|total_drop|recovered|recovery_rate (default total_drop), order=asc|desc, limit (default 10);
+//     the recovery sorts are refused (400) when there is no recovery periode to measure against
+//   - branch, main_branch, type: candidate filters (comma separated)
+//   - jg, kelompok_jabatan: officer filters (comma separated), applied to the rmft board
+func (c *LeaderboardController) GetLeaderboards(ctx *fiber.Ctx) error {
+	boards := pagination.SplitList(ctx.Query("by"))
+	if len(boards) == 0 {
+		boards = leaderboardBoardOrder
+	}
+	for _, board := range boards {
+		if _, ok := leaderboardBoards[board]; !ok {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("invalid by: %s", board),
+			})
+		}
+	}
+
+	q, err := c.parseLeaderboardQuery(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if q == nil {
+		return ctx.JSON(fiber.Map{
+			"periode":            nil,
+			"recovery_periode":   nil,
+			"recovery_available": false,
+			"leaderboards":       fiber.Map{},
+		})
+	}
+	if err := applyDI319DataScope(c.DB, ctx, q.Filter); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load data scope",
+		})
+	}
+
+	results := make(map[string][]leaderboardEntry, len(boards))
+	var mu sync.Mutex
+	var failures []string
+
+	var wg sync.WaitGroup
+	for _, board := range boards {
+		wg.Add(1)
+		go func(board string) {
+			defer wg.Done()
+			entries, err := c.rankLeaderboard(board, q)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", board, err))
+				return
+			}
+			results[board] = entries
+		}(board)
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":    "Failed to compute leaderboards",
+			"failures": failures,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"periode":            q.Periode.Format("2006-01-02"),
+		"recovery_periode":   formatOptionalDate(q.RecoveryPeriode),
+		"recovery_available": q.RecoveryPeriode != nil,
+		"sort":               q.Sort,
+		"leaderboards":       results,
+	})
+}
+
+// GetRMFTScorecard - One officer's leaderboard numbers for every periode they had candidates in
+// (GET /api/leaderboards/rmft/:pn?periode_from=&periode_to=), each measured against the following
+// snapshot periode, so periods compare on the same terms
+func (c *LeaderboardController) GetRMFTScorecard(ctx *fiber.Ctx) error {
+	pn := strings.ToUpper(strings.TrimSpace(ctx.Params("pn")))
+	if !strings.HasPrefix(pn, "PN") {
+		pn = "PN" + pn
+	}
+
+	periodeFilter, err := pagination.ParseFilters(ctx, []pagination.FilterField{
+		{Param: "periode", Column: "periode", Kind: pagination.FilterDateRange},
+	})
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	scope, err := di319ScopeFilter(c.DB, ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load data scope",
+		})
+	}
+	periodeFilter.Add(scope.SQL(), scope.Args...)
+
+	var periodes []time.Time
+	err = periodeFilter.Apply(c.DB.Table("di319").Where("pn_pengelola = ? AND qualifying_rule <> ''", pn)).
+		Distinct("periode").Order("periode").Pluck("periode", &periodes).Error
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var officer struct {
+		PN                  string
+		NamaLengkap         string
+		JG                  string
+		KelompokJabatanRMFT string
+		Kanca               string
+	}
+	c.DB.Table("rfmts").Select("pn, nama_lengkap, jg, kelompok_jabatan_rmft, kanca").
+		Where("deleted_at IS NULL AND pn IN ?", []string{pn, strings.TrimPrefix(pn, "PN")}).
+		Order("created_at DESC").Limit(1).Scan(&officer)
+
+	scorecard := make([]fiber.Map, 0, len(periodes))
+	for _, periode := range periodes {
+		recovery, err := c.nextSnapshotPeriode(periode)
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		filter := &pagination.Filter{}
+		filter.Add("pn_pengelola = ?", pn)
+		filter.Add(scope.SQL(), scope.Args...)
+		entries, err := c.rankLeaderboard("rmft", &leaderboardQuery{
+			Periode:         periode,
+			RecoveryPeriode: recovery,
+			Filter:          filter,
+			Sort:            "total_drop",
+			Desc:            true,
+			Limit:           1,
+		})
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		entry := entries[0]
+		scorecard = append(scorecard, fiber.Map{
+			"periode":            periode.Format("2006-01-02"),
+			"recovery_periode":   formatOptionalDate(recovery),
+			"recovery_available": recovery != nil,
+			"candidates":         entry.Candidates,
+			"total_drop":         entry.TotalDrop,
+			"followed_up":        entry.FollowedUp,
+			"recovered":          entry.Recovered,
+			"recovery_rate":      entry.RecoveryRate,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"pn":                    pn,
+		"nama_lengkap":          officer.NamaLengkap,
+		"jg":                    officer.JG,
+		"kelompok_jabatan_rmft": officer.KelompokJabatanRMFT,
+		"kanca":                 officer.Kanca,
+		"scorecard":             scorecard,
+	})
+}
+
+// parseLeaderboardQuery - Read the ranking parameters. Returns nil when nothing has been imported yet.
+func (c *LeaderboardController) parseLeaderboardQuery(ctx *fiber.Ctx) (*leaderboardQuery, error) {
+	q := &leaderboardQuery{
+		Sort:            ctx.Query("sort", "total_drop"),
+		Desc:            true,
+		Limit:           10,
+		JG:              pagination.SplitList(ctx.Query("jg")),
+		KelompokJabatan: pagination.SplitList(ctx.Query("kelompok_jabatan")),
+	}
+
+	if _, ok := leaderboardSorts[q.Sort]; !ok {
+		return nil, fmt.Errorf("invalid sort: %s", q.Sort)
+	}
+	switch strings.ToLower(ctx.Query("order")) {
+	case "", "desc":
+	case "asc":
+		q.Desc = false
+	default:
+		return nil, fmt.Errorf("invalid order: %s", ctx.Query("order"))
+	}
+	if raw := ctx.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit: %s", raw)
+		}
+		q.Limit = pagination.ClampLimit(limit)
+	}
+
+	var err error
+	if q.Filter, err = pagination.ParseFilters(ctx, leaderboardFilterFields); err != nil {
+		return nil, err
+	}
+
+	if raw := ctx.Query("periode"); raw != "" {
+		if q.Periode, err = time.Parse("2006-01-02", raw); err != nil {
+			return nil, fmt.Errorf("invalid periode: %s (expected yyyy-mm-dd)", raw)
+		}
+	} else {
+		var latest sql.NullTime
+		if err := c.DB.Raw("SELECT MAX(periode) FROM di319").Row().Scan(&latest); err != nil {
+			return nil, err
+		}
+		if !latest.Valid {
+			return nil, nil
+		}
+		q.Periode = latest.Time
+	}
+
+	if raw := ctx.Query("recovery_periode"); raw != "" {
+		recovery, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recovery_periode: %s (expected yyyy-mm-dd)", raw)
+		}
+		if !recovery.After(q.Periode) {
+			return nil, fmt.Errorf("recovery_periode must be after periode")
+		}
+		q.RecoveryPeriode = &recovery
+	} else if q.RecoveryPeriode, err = c.nextSnapshotPeriode(q.Periode); err != nil {
+		return nil, err
+	}
+	if q.RecoveryPeriode == nil && leaderboardRecoverySorts[q.Sort] {
+		return nil, fmt.Errorf("sort=%s needs a recovery periode: no snapshot was imported after %s",
+			q.Sort, q.Periode.Format("2006-01-02"))
+	}
+
+	return q, nil
+}
+
+// nextSnapshotPeriode - The first snapshot periode after periode (nil if none was imported)
+func (c *LeaderboardController) nextSnapshotPeriode(periode time.Time) (*time.Time, error) {
+	var next sql.NullTime
+	err := c.DB.Raw("SELECT MIN(periode) FROM di319_snapshot WHERE periode > ?", periode.Format("2006-01-02")).
+		Row().Scan(&next)
+	if err != nil || !next.Valid {
+		return nil, err
+	}
+	return &next.Time, nil
+}
+
+// rankLeaderboard - Candidates of the periode grouped by the board's key, ranked by q.Sort
+func (c *LeaderboardController) rankLeaderboard(board string, q *leaderboardQuery) ([]leaderboardEntry, error) {
+	dim := leaderboardBoards[board]
+	label := dim.Label
+	if label == "" {
+		label = "''"
+	}
+
+	selects := fmt.Sprintf(`%s AS name, %s AS label, COUNT(*) AS candidates,
+		COALESCE(SUM(CASE WHEN d.drop_amount > 0 THEN d.drop_amount END), 0) AS total_drop`, dim.Key, label)
+	args := []interface{}{q.Periode.Format("2006-01-02")}
+	args = append(args, q.Filter.Args...)
+
+	from := " FROM (SELECT * FROM di319 WHERE periode = ? AND qualifying_rule <> '' AND " + q.Filter.SQL() + ") d"
+	if q.RecoveryPeriode != nil {
+		// Recovered: the balance regained by the recovery periode, at most the drop itself
+		selects += `, COUNT(f.norek) AS followed_up,
+			COALESCE(SUM(LEAST(GREATEST(f.balance - d.balance, 0), GREATEST(COALESCE(d.drop_amount, 0), 0))), 0) AS recovered,
+			ROUND(100 * COALESCE(SUM(LEAST(GREATEST(f.balance - d.balance, 0), GREATEST(COALESCE(d.drop_amount, 0), 0))), 0)
+				/ NULLIF(SUM(CASE WHEN d.drop_amount > 0 THEN d.drop_amount END), 0), 2) AS recovery_rate`
+		from += " LEFT JOIN (SELECT norek, MAX(balance) AS balance FROM di319_snapshot WHERE periode = ? GROUP BY norek) f ON f.norek = d.norek"
+		args = append(args, q.RecoveryPeriode.Format("2006-01-02"))
+	} else {
+		selects += ", 0 AS followed_up, NULL AS recovered, NULL AS recovery_rate"
+	}
+
+	joins := dim.Joins
+	if board == "rmft" {
+		selects += ", MAX(rf.jg) AS jg, MAX(rf.kelompok_jabatan_rmft) AS kelompok_jabatan"
+		officers := &pagination.Filter{}
+		officers.Add("deleted_at IS NULL")
+		if len(q.JG) > 0 {
+			officers.Add("jg IN ?", q.JG)
+		}
+		if len(q.KelompokJabatan) > 0 {
+			officers.Add("kelompok_jabatan_rmft IN ?", q.KelompokJabatan)
+		}
+		// DI319 writes "PN123456", RFMT may store "123456". Officers missing from RFMT are still
+		// ranked unless an officer filter asks for a match.
+		join := " LEFT JOIN"
+		if len(q.JG) > 0 || len(q.KelompokJabatan) > 0 {
+			join = " JOIN"
+		}
+		joins = join + ` (SELECT CONCAT('PN', TRIM(LEADING 'PN' FROM UPPER(pn))) AS pn, MAX(nama_lengkap) AS nama_lengkap,
+			MAX(jg) AS jg, MAX(kelompok_jabatan_rmft) AS kelompok_jabatan_rmft
+			FROM rfmts WHERE ` + officers.SQL() + ` GROUP BY 1) rf ON rf.pn = d.pn_pengelola`
+		args = append(args, officers.Args...)
+	}
+
+	direction := "DESC"
+	if !q.Desc {
+		direction = "ASC"
+	}
+	query := fmt.Sprintf("SELECT %s%s%s GROUP BY %s ORDER BY %s IS NULL, %s %s, name LIMIT ?",
+		selects, from, joins, dim.Key, leaderboardSorts[q.Sort], leaderboardSorts[q.Sort], direction)
+	args = append(args, q.Limit)
+
+	var entries []leaderboardEntry
+	if err := c.DB.Raw(query, args...).Scan(&entries).Error; err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	if entries == nil {
+		entries = []leaderboardEntry{}
+	}
+	return entries, nil
+}
+
+// formatOptionalDate - yyyy-mm-dd, or nil
+func formatOptionalDate(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,671 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"net/mail"
+	"net/url"
+	"os"
+	"pipeline-backend/models"
+	"pipeline-backend/money"
+	"pipeline-backend/pagination"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// notificationKindNewCandidates - Digest of the drop candidates newly assigned to an officer
+const notificationKindNewCandidates = "di319_new_candidates"
+
+// notificationDigestRows - Accounts listed in one digest; the rest are only counted
+const notificationDigestRows = 50
+
+// notificationClaimTimeout - How long a digest stays claimed (status sending) before another run may take it
+// over; only reached when the instance sending it stopped mid-delivery
+const notificationClaimTimeout = 15 * time.Minute
+
+type NotificationController struct {
+	DB *gorm.DB
+}
+
+func NewNotificationController(db *gorm.DB) *NotificationController {
+	return &NotificationController{DB: db}
+}
+
+// candidateDigest - What the new candidates templates show
+type candidateDigest struct {
+	PN             string
+	Nama           string
+	Periode        string
+	Count          int64
+	TotalDrop      string
+	Accounts       []candidateDigestRow
+	More           int64
+	UnsubscribeURL string
+}
+
+type candidateDigestRow struct {
+	NoRek   string
+	Nama    string
+	Type    string
+	Branch  string
+	Balance string
+	Drop    string
+	DropPct string
+}
+
+// newCandidatesSubject, newCandidatesText, newCandidatesHTML - The digest in Bahasa Indonesia
+var (
+	newCandidatesSubject = template.Must(template.New("subject").Parse(
+		`[Pipeline] {{.Count}} rekening baru kelolaan Anda - periode {{.Periode}}`))
+
+	newCandidatesText = template.Must(template.New("text").Parse(`Yth. {{if .Nama}}{{.Nama}}{{else}}Bapak/Ibu{{end}} ({{.PN}}),
+
+Berdasarkan data DI319 periode {{.Periode}}, terdapat {{.Count}} rekening baru dalam kelolaan Anda yang saldonya turun signifikan dan perlu segera ditindaklanjuti. Total penurunan saldo: Rp {{.TotalDrop}}.
+
+{{range .Accounts}}- {{.NoRek}} | {{.Nama}} | {{.Type}} | Uker {{.Branch}} | Saldo Rp {{.Balance}} | Turun Rp {{.Drop}} ({{.DropPct}}%)
+{{end}}{{if .More}}... dan {{.More}} rekening lainnya. Daftar lengkap tersedia di aplikasi Pipeline.
+{{end}}
+Terima kasih.
+
+--
+Email ini dikirim otomatis oleh Pipeline, mohon tidak membalas email ini.
+Untuk berhenti menerima notifikasi ini, buka: {{.UnsubscribeURL}}
+`))
+
+	newCandidatesHTML = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html lang="id"><body style="font-family: Arial, sans-serif; font-size: 14px; color: #222;">
+<p>Yth. {{if .Nama}}{{.Nama}}{{else}}Bapak/Ibu{{end}} ({{.PN}}),</p>
+<p>Berdasarkan data DI319 periode <strong>{{.Periode}}</strong>, terdapat <strong>{{.Count}} rekening baru</strong>
+dalam kelolaan Anda yang saldonya turun signifikan dan perlu segera ditindaklanjuti.
+Total penurunan saldo: <strong>Rp {{.TotalDrop}}</strong>.</p>
+<table cellpadding="6" cellspacing="0" border="1" style="border-collapse: collapse; font-size: 13px;">
+<tr style="background: #e1e8f0;"><th>No Rekening</th><th>Nama</th><th>Type</th><th>Uker</th><th>Saldo (Rp)</th><th>Turun (Rp)</th><th>Turun (%)</th></tr>
+{{range .Accounts}}<tr><td>{{.NoRek}}</td><td>{{.Nama}}</td><td>{{.Type}}</td><td>{{.Branch}}</td><td align="right">{{.Balance}}</td><td align="right">{{.Drop}}</td><td align="right">{{.DropPct}}</td></tr>
+{{end}}</table>
+{{if .More}}<p>... dan {{.More}} rekening lainnya. Daftar lengkap tersedia di aplikasi Pipeline.</p>{{end}}
+<p>Terima kasih.</p>
+<p style="font-size: 12px; color: #777;">Email ini dikirim otomatis oleh Pipeline, mohon tidak membalas email ini.<br>
+<a href="{{.UnsubscribeURL}}">Berhenti menerima notifikasi ini</a></p>
+</body></html>
+`))
+)
+
+// NotifyNewCandidatesAfterImport - Send the new candidates digests once an import is done, when it touched
+// the latest periode (backfilling older periodes notifies nobody). Skipped without SMTP_HOST or with
+// NOTIFY_AFTER_IMPORT=false.
+func NotifyNewCandidatesAfterImport(db *gorm.DB, touched []time.Time) {
+	if !notifyNewCandidatesEnabled() || len(touched) == 0 {
+		return
+	}
+
+	var latest sql.NullTime
+	if err := db.Raw("SELECT MAX(periode) FROM di319").Row().Scan(&latest); err != nil || !latest.Valid {
+		return
+	}
+	for _, periode := range touched {
+		if periode.Equal(latest.Time) {
+			sent, skipped, failed, err := sendNewCandidateDigests(db, latest.Time)
+			if err != nil {
+				log.Printf("❌ New candidate notifications for %s failed: %v", latest.Time.Format("2006-01-02"), err)
+				return
+			}
+			log.Printf("✉️  New candidate notifications for %s: %d sent, %d skipped, %d failed",
+				latest.Time.Format("2006-01-02"), sent, skipped, failed)
+			return
+		}
+	}
+}
+
+// notifyNewCandidatesEnabled - Whether imports trigger the digests (SMTP configured, NOTIFY_AFTER_IMPORT not false)
+func notifyNewCandidatesEnabled() bool {
+	if os.Getenv("NOTIFY_AFTER_IMPORT") == "false" {
+		return false
+	}
+	_, err := loadMailConfig()
+	return err == nil
+}
+
+// sendNewCandidateDigests - One digest per officer with the candidates of periode that were not already
+// their candidates in the previous periode. Digests already sent are not sent again; failed ones are retried;
+// one being delivered by another run or instance is skipped.
+func sendNewCandidateDigests(db *gorm.DB, periode time.Time) (sent, skipped, failed int, err error) {
+	mailer, err := loadMailConfig()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	digests, err := buildNewCandidateDigests(db, periode)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	for _, digest := range digests {
+		switch status := deliverNewCandidateDigest(db, mailer, periode, digest); status {
+		case models.NotificationSent:
+			sent++
+		case models.NotificationFailed:
+			failed++
+		default:
+			skipped++
+		}
+	}
+	return sent, skipped, failed, nil
+}
+
+// buildNewCandidateDigests - Read the new candidates of periode into one digest per officer. The rows are
+// closed before anything is mailed, so a slow SMTP server never keeps the query open.
+func buildNewCandidateDigests(db *gorm.DB, periode time.Time) ([]*candidateDigest, error) {
+	day := periode.Format("2006-01-02")
+	var previous sql.NullTime
+	if err := db.Raw("SELECT MAX(periode) FROM di319 WHERE periode < ?", day).Row().Scan(&previous); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT * FROM di319 d WHERE d.periode = ? AND d.qualifying_rule <> '' AND d.pn_pengelola <> ''`
+	args := []interface{}{day}
+	if previous.Valid {
+		query += ` AND NOT EXISTS (SELECT 1 FROM di319 p WHERE p.periode = ? AND p.norek = d.norek
+			AND p.pn_pengelola = d.pn_pengelola AND p.qualifying_rule <> '')`
+		args = append(args, previous.Time.Format("2006-01-02"))
+	}
+	rows, err := db.Raw(query+" ORDER BY d.pn_pengelola, d.drop_amount IS NULL, d.drop_amount DESC, d.norek", args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Rows arrive grouped by PN: a new digest starts with each officer
+	var digests []*candidateDigest
+	var digest *candidateDigest
+	var totalDrop money.Amount
+	for rows.Next() {
+		var row models.DI319
+		if err := db.ScanRows(rows, &row); err != nil {
+			return nil, err
+		}
+		if digest == nil || digest.PN != row.PNPengelola {
+			if digest != nil {
+				digest.TotalDrop = formatReportAmount(totalDrop)
+			}
+			digest = &candidateDigest{PN: row.PNPengelola, Periode: day}
+			digests = append(digests, digest)
+			totalDrop = 0
+		}
+
+		digest.Count++
+		if row.DropAmount != nil && *row.DropAmount > 0 {
+			totalDrop += *row.DropAmount
+		}
+		if len(digest.Accounts) >= notificationDigestRows {
+			digest.More++
+			continue
+		}
+		drop := "-"
+		if row.DropAmount != nil {
+			drop = formatReportAmount(*row.DropAmount)
+		}
+		digest.Accounts = append(digest.Accounts, candidateDigestRow{
+			NoRek:   row.NoRek,
+			Nama:    row.Nama,
+			Type:    row.Type,
+			Branch:  row.Branch,
+			Balance: formatReportAmount(row.Balance),
+			Drop:    drop,
+			DropPct: formatReportPct(row.DropPct),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if digest != nil {
+		digest.TotalDrop = formatReportAmount(totalDrop)
+	}
+	return digests, nil
+}
+
+// deliverNewCandidateDigest - Send one officer's digest unless it already went out, recording the outcome
+func deliverNewCandidateDigest(db *gorm.DB, mailer *mailConfig, periode time.Time, digest *candidateDigest) string {
+	notification := models.Notification{
+		Kind:    notificationKindNewCandidates,
+		Periode: periode,
+		PN:      digest.PN,
+		Status:  models.NotificationPending,
+	}
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&notification).Error
+	if err == nil {
+		err = db.Where("kind = ? AND periode = ? AND pn = ?", notification.Kind, periode.Format("2006-01-02"), digest.PN).
+			First(&notification).Error
+	}
+	if err != nil {
+		log.Printf("⚠️  Notification for %s: %v", digest.PN, err)
+		return models.NotificationFailed
+	}
+	if notification.Status == models.NotificationSent {
+		return models.NotificationSent
+	}
+
+	// Claim the digest: of several runs (or instances) only the one that moves it to sending mails it
+	now := time.Now()
+	claim := db.Model(&models.Notification{}).
+		Where("id = ? AND (status IN ? OR (status = ? AND updated_at < ?))", notification.ID,
+			[]string{models.NotificationPending, models.NotificationFailed, models.NotificationOptedOut, models.NotificationNoAddress},
+			models.NotificationSending, now.Add(-notificationClaimTimeout)).
+		Updates(map[string]interface{}{"status": models.NotificationSending, "updated_at": now})
+	if claim.Error != nil {
+		log.Printf("⚠️  Notification for %s: %v", digest.PN, claim.Error)
+		return models.NotificationFailed
+	}
+	if claim.RowsAffected != 1 {
+		return models.NotificationSending
+	}
+
+	updates := map[string]interface{}{"accounts": digest.Count, "error": ""}
+	record := func(status string) string {
+		updates["status"] = status
+		if err := db.Model(&notification).Updates(updates).Error; err != nil {
+			log.Printf("⚠️  Failed to record notification %d: %v", notification.ID, err)
+		}
+		return status
+	}
+
+	var recipient models.NotificationRecipient
+	if err := db.Where("pn = ?", digest.PN).First(&recipient).Error; err != nil || recipient.Email == "" {
+		return record(models.NotificationNoAddress)
+	}
+	updates["email"] = recipient.Email
+	if recipient.OptOut {
+		return record(models.NotificationOptedOut)
+	}
+
+	db.Raw(`SELECT COALESCE(MAX(nama_lengkap), '') FROM rfmts WHERE deleted_at IS NULL
+		AND CONCAT('PN', TRIM(LEADING 'PN' FROM UPPER(pn))) = ?`, digest.PN).Row().Scan(&digest.Nama)
+	digest.UnsubscribeURL = notificationUnsubscribeURL(recipient.OptOutToken)
+
+	subject, msg, err := renderNewCandidateDigest(mailer, recipient.Email, digest)
+	updates["subject"] = subject
+	updates["attempts"] = notification.Attempts + 1
+	if err == nil {
+		err = mailer.send(recipient.Email, msg)
+	}
+	if err != nil {
+		log.Printf("❌ Notification to %s (%s) failed: %v", digest.PN, recipient.Email, err)
+		updates["error"] = err.Error()
+		return record(models.NotificationFailed)
+	}
+	updates["sent_at"] = time.Now()
+	return record(models.NotificationSent)
+}
+
+// renderNewCandidateDigest - Subject and MIME message of one digest
+func renderNewCandidateDigest(mailer *mailConfig, to string, digest *candidateDigest) (string, []byte, error) {
+	var subject, text, html bytes.Buffer
+	if err := newCandidatesSubject.Execute(&subject, digest); err != nil {
+		return "", nil, err
+	}
+	if err := newCandidatesText.Execute(&text, digest); err != nil {
+		return "", nil, err
+	}
+	if err := newCandidatesHTML.Execute(&html, digest); err != nil {
+		return "", nil, err
+	}
+	msg, err := buildMail(mailer.From, to, subject.String(), text.String(), html.String(), digest.UnsubscribeURL)
+	return subject.String(), msg, err
+}
+
+// notificationUnsubscribeURL - Public opt-out link (NOTIFY_BASE_URL, default http://localhost:8080)
+func notificationUnsubscribeURL(token string) string {
+	base := strings.TrimRight(os.Getenv("NOTIFY_BASE_URL"), "/")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	return base + "/api/notifications/unsubscribe?token=" + url.QueryEscape(token)
+}
+
+// normalizePN - DI319 keys officers as "PN123456"
+func normalizePN(pn string) string {
+	pn = strings.ToUpper(strings.TrimSpace(pn))
+	if pn != "" && !strings.HasPrefix(pn, "PN") {
+		pn = "PN" + pn
+	}
+	return pn
+}
+
+// notificationListSpec - Paging, sorting and filtering of GET /api/notifications
+var notificationListSpec = pagination.Spec{
+	Table: "notifications",
+	Sorts: map[string]pagination.Sort{
+		"id":      {},
+		"periode": {Column: "periode"},
+		"pn":      {Column: "pn"},
+	},
+	DefaultSort: "id",
+	DefaultDesc: true,
+	Filters: []pagination.FilterField{
+		{Param: "kind", Column: "kind", Kind: pagination.FilterIn},
+		{Param: "status", Column: "status", Kind: pagination.FilterIn},
+		{Param: "pn", Column: "pn", Kind: pagination.FilterIn},
+		{Param: "periode", Column: "periode", Kind: pagination.FilterDateRange},
+	},
+}
+
+// notificationRecipientListSpec - Paging and filtering of GET /api/notifications/recipients
+var notificationRecipientListSpec = pagination.Spec{
+	Table: "notification_recipients",
+	Sorts: map[string]pagination.Sort{
+		"id": {},
+		"pn": {Column: "pn"},
+	},
+	DefaultSort:   "pn",
+	SearchColumns: []string{"pn", "email"},
+}
+
+// GetAll - Delivery log (GET /api/notifications?status=&pn=&periode=, admin only)
+func (c *NotificationController) GetAll(ctx *fiber.Ctx) error {
+	params, err := pagination.Parse(ctx, notificationListSpec)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	result, err := pagination.List[models.Notification](c.DB, c.DB.Model(&models.Notification{}), params)
+	if err == pagination.ErrInvalidCursor {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return pagination.Respond(ctx, params, result)
+}
+
+// SendNewCandidates - Send (or retry) the new candidates digests of a periode
+// (POST /api/notifications/di319/send, body {"periode": "yyyy-mm-dd"}, default the latest; admin only).
+// Runs in the background; follow GET /api/notifications?periode=.
+func (c *NotificationController) SendNewCandidates(ctx *fiber.Ctx) error {
+	var req struct {
+		Periode string `json:"periode"`
+	}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+	if _, err := loadMailConfig(); err != nil {
+		return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var periode time.Time
+	if req.Periode != "" {
+		var err error
+		if periode, err = time.Parse("2006-01-02", req.Periode); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("invalid periode: %s (expected yyyy-mm-dd)", req.Periode),
+			})
+		}
+	} else {
+		var latest sql.NullTime
+		if err := c.DB.Raw("SELECT MAX(periode) FROM di319").Row().Scan(&latest); err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if !latest.Valid {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "No DI319 data imported",
+			})
+		}
+		periode = latest.Time
+	}
+
+	go func() {
+		sent, skipped, failed, err := sendNewCandidateDigests(c.DB, periode)
+		if err != nil {
+			log.Printf("❌ New candidate notifications for %s failed: %v", periode.Format("2006-01-02"), err)
+			return
+		}
+		log.Printf("✉️  New candidate notifications for %s: %d sent, %d skipped, %d failed",
+			periode.Format("2006-01-02"), sent, skipped, failed)
+	}()
+
+	userID, username := auditUser(ctx)
+	writeAuditLog(c.DB, userID, username, "send", "notification", "periode="+periode.Format("2006-01-02"), 0)
+
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Notifications are being sent",
+		"periode": periode.Format("2006-01-02"),
+	})
+}
+
+// SendTest - Send a sample digest to check the SMTP settings, e.g. against MailHog
+// (POST /api/notifications/test, body {"email": "..."}; admin only)
+func (c *NotificationController) SendTest(ctx *fiber.Ctx) error {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	address, err := mail.ParseAddress(req.Email)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid email address",
+		})
+	}
+	mailer, err := loadMailConfig()
+	if err != nil {
+		return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	digest := &candidateDigest{
+		PN:        "PN000000",
+		Nama:      "Contoh RMFT",
+		Periode:   time.Now().Format("2006-01-02"),
+		Count:     1,
+		TotalDrop: formatReportAmount(money.FromInt(25000000)),
+		Accounts: []candidateDigestRow{{
+			NoRek: "000000000000000", Nama: "NASABAH CONTOH", Type: "SA", Branch: "00000",
+			Balance: formatReportAmount(money.FromInt(5000000)), Drop: formatReportAmount(money.FromInt(25000000)), DropPct: "83,33",
+		}},
+		UnsubscribeURL: notificationUnsubscribeURL("test"),
+	}
+	_, msg, err := renderNewCandidateDigest(mailer, address.Address, digest)
+	if err == nil {
+		err = mailer.send(address.Address, msg)
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to send test email: %v", err),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "Test email sent to " + address.Address,
+	})
+}
+
+// GetRecipients - Officer email addresses and opt-outs (admin only)
+func (c *NotificationController) GetRecipients(ctx *fiber.Ctx) error {
+	params, err := pagination.Parse(ctx, notificationRecipientListSpec)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	result, err := pagination.List[models.NotificationRecipient](c.DB, c.DB.Model(&models.NotificationRecipient{}), params)
+	if err == pagination.ErrInvalidCursor {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return pagination.Respond(ctx, params, result)
+}
+
+// SetRecipient - Set an officer's email and opt-out (PUT /api/notifications/recipients/:pn,
+// body {"email": "...", "opt_out": false}; admin only)
+func (c *NotificationController) SetRecipient(ctx *fiber.Ctx) error {
+	pn := normalizePN(ctx.Params("pn"))
+	var req struct {
+		Email  string `json:"email"`
+		OptOut *bool  `json:"opt_out"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	address, err := mail.ParseAddress(req.Email)
+	if err != nil || len(address.Address) > 255 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid email address",
+		})
+	}
+
+	var recipient models.NotificationRecipient
+	err = c.DB.Where("pn = ?", pn).First(&recipient).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		recipient = models.NotificationRecipient{PN: pn, OptOutToken: randomToken(32)}
+	} else if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	recipient.Email = address.Address
+	if req.OptOut != nil && *req.OptOut != recipient.OptOut {
+		recipient.OptOut = *req.OptOut
+		recipient.OptOutAt = nil
+		if recipient.OptOut {
+			now := time.Now()
+			recipient.OptOutAt = &now
+		}
+	}
+	if err := c.DB.Save(&recipient).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "Recipient saved successfully",
+		"data":    recipient,
+	})
+}
+
+// DeleteRecipient - Remove an officer's address (admin only)
+func (c *NotificationController) DeleteRecipient(ctx *fiber.Ctx) error {
+	result := c.DB.Where("pn = ?", normalizePN(ctx.Params("pn"))).Delete(&models.NotificationRecipient{})
+	if result.Error != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": result.Error.Error(),
+		})
+	}
+	if result.RowsAffected == 0 {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Recipient not found",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "Recipient deleted successfully",
+	})
+}
+
+// UnsubscribeForm - Public opt-out link from the digest footer (GET /api/notifications/unsubscribe?token=).
+// Only asks for confirmation: mail scanners follow links, so opening one must not opt anybody out.
+func (c *NotificationController) UnsubscribeForm(ctx *fiber.Ctx) error {
+	token := ctx.Query("token")
+	ctx.Type("html", "utf-8")
+	if token == "" {
+		return ctx.Status(fiber.StatusBadRequest).SendString(unsubscribePage("Tautan berhenti berlangganan tidak valid."))
+	}
+
+	var recipient models.NotificationRecipient
+	if err := c.DB.Where("opt_out_token = ?", token).First(&recipient).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ctx.Status(fiber.StatusNotFound).SendString(unsubscribePage("Tautan berhenti berlangganan tidak valid."))
+		}
+		return ctx.Status(fiber.StatusInternalServerError).SendString(unsubscribePage("Terjadi kesalahan, silakan coba lagi."))
+	}
+	if recipient.OptOut {
+		return ctx.SendString(unsubscribePage("Anda sudah berhenti menerima notifikasi email dari Pipeline."))
+	}
+
+	var page bytes.Buffer
+	if err := unsubscribeConfirmHTML.Execute(&page, token); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).SendString(unsubscribePage("Terjadi kesalahan, silakan coba lagi."))
+	}
+	return ctx.Send(page.Bytes())
+}
+
+// Unsubscribe - Opt out (POST /api/notifications/unsubscribe?token=), from the confirmation form or a mail
+// client's RFC 8058 one-click request. The form also sends the token as a field.
+func (c *NotificationController) Unsubscribe(ctx *fiber.Ctx) error {
+	token := ctx.Query("token")
+	if token == "" {
+		token = ctx.FormValue("token")
+	}
+	ctx.Type("html", "utf-8")
+	if token == "" {
+		return ctx.Status(fiber.StatusBadRequest).SendString(unsubscribePage("Tautan berhenti berlangganan tidak valid."))
+	}
+
+	now := time.Now()
+	result := c.DB.Model(&models.NotificationRecipient{}).Where("opt_out_token = ? AND opt_out = ?", token, false).
+		Updates(map[string]interface{}{"opt_out": true, "opt_out_at": now})
+	if result.Error != nil {
+		return ctx.Status(fiber.StatusInternalServerError).SendString(unsubscribePage("Terjadi kesalahan, silakan coba lagi."))
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		c.DB.Model(&models.NotificationRecipient{}).Where("opt_out_token = ?", token).Count(&count)
+		if count == 0 {
+			return ctx.Status(fiber.StatusNotFound).SendString(unsubscribePage("Tautan berhenti berlangganan tidak valid."))
+		}
+	}
+
+	return ctx.SendString(unsubscribePage("Anda telah berhenti menerima notifikasi email dari Pipeline."))
+}
+
+// unsubscribeConfirmHTML - Confirmation page of the opt-out link; posts back to the same URL
+var unsubscribeConfirmHTML = htmltemplate.Must(htmltemplate.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="id"><body style="font-family: Arial, sans-serif;">
+<p>Berhenti menerima notifikasi email kandidat baru dari Pipeline?</p>
+<form method="post">
+<input type="hidden" name="token" value="{{.}}">
+<button type="submit">Ya, berhenti berlangganan</button>
+</form>
+</body></html>`))
+
+// unsubscribePage - Minimal HTML answer for the opt-out link
+func unsubscribePage(message string) string {
+	return "<!DOCTYPE html><html lang=\"id\"><body style=\"font-family: Arial, sans-serif;\"><p>" +
+		htmltemplate.HTMLEscapeString(message) + "</p></body></html>"
+}
@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestUnsubscribeConfirmHTML(t *testing.T) {
+	var page bytes.Buffer
+	if err := unsubscribeConfirmHTML.Execute(&page, `tok"en<x>`); err != nil {
+		t.Fatal(err)
+	}
+	html := page.String()
+	if !strings.Contains(html, `<form method="post">`) {
+		t.Errorf("confirmation must post back to the link: %s", html)
+	}
+	if strings.Contains(html, `tok"en<x>`) || !strings.Contains(html, `value="tok&#34;en&lt;x&gt;"`) {
+		t.Errorf("token is not escaped: %s", html)
+	}
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+// smtpTimeout - Budget for one delivery (connect, handshake and DATA)
+const smtpTimeout = 30 * time.Second
+
+// mailConfig - SMTP delivery settings:
+//   - SMTP_HOST, SMTP_PORT (default 25); MailHog listens on localhost:1025
+//   - SMTP_USERNAME / SMTP_PASSWORD: PLAIN auth, only sent over TLS (or to localhost)
+//   - SMTP_TLS: starttls (default, used when the server offers it), tls (implicit, port 465) or none
+//   - SMTP_FROM, SMTP_FROM_NAME: sender address and display name
+type mailConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	TLS      string
+	From     mail.Address
+}
+
+var errMailNotConfigured = errors.New("email delivery is not configured (SMTP_HOST is empty)")
+
+// loadMailConfig - Read the SMTP settings from the environment
+func loadMailConfig() (*mailConfig, error) {
+	m := &mailConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		TLS:      strings.ToLower(os.Getenv("SMTP_TLS")),
+		From:     mail.Address{Name: os.Getenv("SMTP_FROM_NAME"), Address: os.Getenv("SMTP_FROM")},
+	}
+	if m.Host == "" {
+		return nil, errMailNotConfigured
+	}
+	if m.Port == "" {
+		m.Port = "25"
+	}
+	if m.TLS == "" {
+		m.TLS = "starttls"
+	}
+	if m.TLS != "starttls" && m.TLS != "tls" && m.TLS != "none" {
+		return nil, fmt.Errorf("invalid SMTP_TLS: %s (expected starttls, tls or none)", m.TLS)
+	}
+	if m.From.Address == "" {
+		m.From.Address = "pipeline@localhost"
+	}
+	if m.From.Name == "" {
+		m.From.Name = "Pipeline"
+	}
+	return m, nil
+}
+
+// send - Deliver one message to one recipient
+func (m *mailConfig) send(to string, msg []byte) error {
+	addr := net.JoinHostPort(m.Host, m.Port)
+	tlsConfig := &tls.Config{ServerName: m.Host}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	if m.TLS == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if m.TLS == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+	if m.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(m.From.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMail - A multipart/alternative (plain text + HTML) UTF-8 message. unsubscribeURL, when set,
+// becomes a one-click List-Unsubscribe header.
+func buildMail(from mail.Address, to, subject, text, html, unsubscribeURL string) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&msg, "%s: %s\r\n", key, value)
+	}
+	header("From", from.String())
+	header("To", to)
+	header("Subject", mime.QEncoding.Encode("UTF-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%s@%s>", randomToken(16), mailDomain(from.Address)))
+	header("MIME-Version", "1.0")
+	header("Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", parts.Boundary()))
+	if unsubscribeURL != "" {
+		header("List-Unsubscribe", "<"+unsubscribeURL+">")
+		header("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// mailDomain - The domain part of an address, for Message-ID
+func mailDomain(address string) string {
+	if _, domain, ok := strings.Cut(address, "@"); ok && domain != "" {
+		return domain
+	}
+	return "localhost"
+}
+
+// randomToken - n random bytes, hex encoded
+func randomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand does not fail on supported platforms
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"testing"
+)
+
+func TestBuildMail(t *testing.T) {
+	from := mail.Address{Name: "Pipeline", Address: "pipeline@bank.example"}
+	tests := []struct {
+		name           string
+		unsubscribeURL string
+	}{
+		{"with opt-out link", "http://localhost:8080/api/notifications/unsubscribe?token=abc"},
+		{"without opt-out link", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := buildMail(from, "rm@bank.example", "Kandidat baru: 3 rekening", "teks ë", "<p>html ë</p>", tt.unsubscribeURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := mail.ReadMessage(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatalf("unparsable message: %v", err)
+			}
+
+			subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			if err != nil || subject != "Kandidat baru: 3 rekening" {
+				t.Errorf("Subject = %q (%v)", subject, err)
+			}
+			if got := msg.Header.Get("List-Unsubscribe"); (got != "") != (tt.unsubscribeURL != "") ||
+				(got != "" && got != "<"+tt.unsubscribeURL+">") {
+				t.Errorf("List-Unsubscribe = %q", got)
+			}
+			if got := msg.Header.Get("List-Unsubscribe-Post"); (got != "") != (tt.unsubscribeURL != "") {
+				t.Errorf("List-Unsubscribe-Post = %q", got)
+			}
+
+			mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			if err != nil || mediaType != "multipart/alternative" {
+				t.Fatalf("Content-Type = %q (%v)", mediaType, err)
+			}
+			parts := multipart.NewReader(msg.Body, params["boundary"])
+			for _, want := range []string{"teks ë", "<p>html ë</p>"} {
+				part, err := parts.NextRawPart()
+				if err != nil {
+					t.Fatalf("missing part %q: %v", want, err)
+				}
+				body, err := io.ReadAll(quotedprintable.NewReader(part))
+				if err != nil || string(body) != want {
+					t.Errorf("part = %q (%v), want %q", body, err, want)
+				}
+			}
+		})
+	}
+}
@@ -2,7 +2,7 @@ package controllers
 
 import (
 	"pipeline-backend/models"
-	"strconv"
+	"pipeline-backend/pagination"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -16,43 +16,41 @@ func NewProductTypeController(db *gorm.DB) *ProductTypeController {
 	return &ProductTypeController{DB: db}
 }
 
+// productTypeListSpec - Paging, sorting and search of GET /api/product-types.
+// The pre-envelope meta.total/page/page_size keys are part of the standard meta, so no legacy keys are needed.
+var productTypeListSpec = pagination.Spec{
+	Table: "product_type",
+	Sorts: map[string]pagination.Sort{
+		"kode_product": {Column: "kode_product"},
+		"nama_product": {Column: "nama_product"},
+		"id":           {},
+	},
+	DefaultSort:   "kode_product",
+	SearchColumns: []string{"kode_product", "nama_product"},
+}
+
 // GetAll - Get all product types with pagination and search
 func (c *ProductTypeController) GetAll(ctx *fiber.Ctx) error {
-	var productTypes []models.ProductType
-	var total int64
-
-	// Pagination
-	page, _ := strconv.Atoi(ctx.Query("page", "1"))
-	pageSize, _ := strconv.Atoi(ctx.Query("page_size", "10"))
-	offset := (page - 1) * pageSize
-
-	// Search
-	search := ctx.Query("search", "")
-
-	query := c.DB.Model(&models.ProductType{})
-
-	if search != "" {
-		query = query.Where("kode_product LIKE ? OR nama_product LIKE ?", "%"+search+"%", "%"+search+"%")
+	params, err := pagination.Parse(ctx, productTypeListSpec)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
-	// Count total
-	query.Count(&total)
-
-	// Get data
-	if err := query.Offset(offset).Limit(pageSize).Order("kode_product ASC").Find(&productTypes).Error; err != nil {
+	result, err := pagination.List[models.ProductType](c.DB, c.DB.Model(&models.ProductType{}), params)
+	if err == pagination.ErrInvalidCursor {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return ctx.JSON(fiber.Map{
-		"data": productTypes,
-		"meta": fiber.Map{
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-		},
-	})
+	return pagination.Respond(ctx, params, result)
 }
 
 // GetByID - Get product type by ID
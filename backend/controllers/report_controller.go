@@ -0,0 +1,609 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"pipeline-backend/models"
+	"pipeline-backend/money"
+	"pipeline-backend/pagination"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// reportTopDrops - Rows in the top drops table
+const reportTopDrops = 15
+
+// reportSlots - Limits concurrent report generation (REPORT_WORKERS, default 2)
+var (
+	reportSlots     chan struct{}
+	reportSlotsOnce sync.Once
+)
+
+func acquireReportSlot() {
+	reportSlotsOnce.Do(func() {
+		reportSlots = make(chan struct{}, clampInt(envInt("REPORT_WORKERS", 2), 1, 16))
+	})
+	reportSlots <- struct{}{}
+}
+
+func releaseReportSlot() {
+	<-reportSlots
+}
+
+type ReportController struct {
+	DB  *gorm.DB
+	Dir string
+}
+
+func NewReportController(db *gorm.DB) *ReportController {
+	dir := os.Getenv("REPORT_DIR")
+	if dir == "" {
+		dir = "reports"
+	}
+	return &ReportController{DB: db, Dir: dir}
+}
+
+// reportListSpec - Paging, sorting and filtering of GET /api/reports
+var reportListSpec = pagination.Spec{
+	Table: "reports",
+	Sorts: map[string]pagination.Sort{
+		"id":      {},
+		"uker":    {Column: "uker"},
+		"periode": {Column: "periode"},
+		"status":  {Column: "status"},
+	},
+	DefaultSort: "id",
+	DefaultDesc: true,
+	Filters: []pagination.FilterField{
+		{Param: "uker", Column: "uker", Kind: pagination.FilterIn},
+		{Param: "region", Column: "region", Kind: pagination.FilterIn},
+		{Param: "batch_id", Column: "batch_id", Kind: pagination.FilterIn},
+		{Param: "status", Column: "status", Kind: pagination.FilterIn},
+		{Param: "template", Column: "template", Kind: pagination.FilterIn},
+		{Param: "periode", Column: "periode", Kind: pagination.FilterDateRange},
+	},
+}
+
+// reportUker - A uker a report is generated for
+type reportUker struct {
+	KodeUker   string
+	MainBranch string
+	Region     string
+}
+
+// Generate - Queue branch reports (POST /api/reports).
+// Body: {"uker": "10272"} for one uker or {"region": "R1"} for every active uker of a region,
+// plus optional "periode" (yyyy-mm or yyyy-mm-dd, default the latest DI319 periode) and
+// "template" (default branch_monthly). Ukers outside the user's data scope are skipped.
+// Returns 202 with the batch; poll GET /api/reports?batch_id= and download when done.
+func (c *ReportController) Generate(ctx *fiber.Ctx) error {
+	var req struct {
+		Template string `json:"template"`
+		Uker     string `json:"uker"`
+		Region   string `json:"region"`
+		Periode  string `json:"periode"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	req.Uker, req.Region = strings.TrimSpace(req.Uker), strings.TrimSpace(req.Region)
+	if (req.Uker == "") == (req.Region == "") {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Either uker or region is required",
+		})
+	}
+	if req.Template == "" {
+		req.Template = "branch_monthly"
+	}
+	if _, ok := reportTemplates[req.Template]; !ok {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid template: %s", req.Template),
+		})
+	}
+
+	periode, err := c.resolveReportPeriode(req.Periode)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, errInvalidReportPeriode) {
+			status = fiber.StatusBadRequest
+		}
+		return ctx.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if periode == nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No imported DI319 periode matches",
+		})
+	}
+
+	ukers, err := c.reportUkers(req.Uker, req.Region)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if len(ukers) == 0 {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No uker found",
+		})
+	}
+
+	scope, err := userDataScope(c.DB, ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load data scope",
+		})
+	}
+	if len(scope) > 0 {
+		var allowed []reportUker
+		for _, u := range ukers {
+			if containsString(scope, u.KodeUker) || containsString(scope, u.MainBranch) {
+				allowed = append(allowed, u)
+			}
+		}
+		if len(allowed) == 0 {
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Outside your data scope",
+			})
+		}
+		ukers = allowed
+	}
+
+	userID, username := auditUser(ctx)
+	batchID, reports, err := c.createReportBatch(req.Template, ukers, *periode, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	for _, report := range reports {
+		go c.runReport(report)
+	}
+	writeAuditLog(c.DB, userID, username, "generate", "report",
+		fmt.Sprintf("template=%s uker=%s region=%s periode=%s", req.Template, req.Uker, req.Region, periode.Format("2006-01-02")),
+		int64(len(reports)))
+
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"batch_id": batchID,
+		"periode":  periode.Format("2006-01-02"),
+		"reports":  reports,
+	})
+}
+
+// reportUkers - One uker by code, or every active uker of a region
+func (c *ReportController) reportUkers(kodeUker, region string) ([]reportUker, error) {
+	query := c.DB.Table("uker").Select("kode_uker, MAX(main_branch) AS main_branch, MAX(region) AS region")
+	if kodeUker != "" {
+		query = query.Where("kode_uker = ?", kodeUker)
+	} else {
+		query = query.Where("region = ? AND ACTIVE = 'Y'", region)
+	}
+	var ukers []reportUker
+	err := query.Group("kode_uker").Order("kode_uker").Scan(&ukers).Error
+	return ukers, err
+}
+
+// createReportBatch - Record one queued report per uker under a new batch
+func (c *ReportController) createReportBatch(templateName string, ukers []reportUker, periode time.Time, userID uint) (string, []models.Report, error) {
+	batchID := uuid.NewString()
+	reports := make([]models.Report, 0, len(ukers))
+	for _, u := range ukers {
+		reports = append(reports, models.Report{
+			Template: templateName,
+			Uker:     u.KodeUker,
+			Region:   u.Region,
+			Periode:  periode,
+			BatchID:  batchID,
+			Status:   models.ReportQueued,
+			UserID:   userID,
+		})
+	}
+	if err := c.DB.CreateInBatches(&reports, 500).Error; err != nil {
+		return "", nil, err
+	}
+	return batchID, reports, nil
+}
+
+// runReport - Generate one queued report, recording the outcome on its row
+func (c *ReportController) runReport(report models.Report) error {
+	acquireReportSlot()
+	defer releaseReportSlot()
+
+	c.DB.Model(&models.Report{}).Where("id = ?", report.ID).Update("status", models.ReportRunning)
+
+	start := time.Now()
+	filename, path, size, err := c.generateReport(report)
+	completed := time.Now()
+	updates := map[string]interface{}{"completed_at": completed}
+	if err != nil {
+		log.Printf("❌ Report %d (%s %s) failed: %v", report.ID, report.Template, report.Uker, err)
+		updates["status"] = models.ReportFailed
+		updates["error"] = err.Error()
+	} else {
+		log.Printf("📄 Report %d (%s %s) generated in %v", report.ID, report.Template, report.Uker, completed.Sub(start))
+		updates["status"] = models.ReportDone
+		updates["filename"] = filename
+		updates["path"] = path
+		updates["size"] = size
+	}
+	if err := c.DB.Model(&models.Report{}).Where("id = ?", report.ID).Updates(updates).Error; err != nil {
+		log.Printf("⚠️  Failed to record report %d: %v", report.ID, err)
+	}
+	return err
+}
+
+// generateReport - Gather the data, render the PDF and write it to the report directory
+func (c *ReportController) generateReport(report models.Report) (string, string, int64, error) {
+	tpl, ok := reportTemplates[report.Template]
+	if !ok {
+		return "", "", 0, fmt.Errorf("unknown template: %s", report.Template)
+	}
+	data, err := c.branchReportData(report.Uker, report.Periode)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return "", "", 0, fmt.Errorf("failed to create report directory: %w", err)
+	}
+	filename := fmt.Sprintf("%s_%s_%s.pdf", report.Template, report.Uker, report.Periode.Format("2006-01-02"))
+	path := filepath.Join(c.Dir, fmt.Sprintf("%d_%s", report.ID, filename))
+
+	// Render to a temp file first so a failed run never leaves a truncated PDF behind
+	f, err := os.CreateTemp(c.Dir, "report-*.part")
+	if err != nil {
+		return "", "", 0, err
+	}
+	if err := renderReportPDF(tpl, data, f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", "", 0, err
+	}
+	info, err := f.Stat()
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", "", 0, err
+	}
+	return filename, path, info.Size(), nil
+}
+
+// branchReportData - Uker details, candidate counts, top drops, RMFT breakdown and recovery status
+// of one uker in one periode. Recovery is measured against the next snapshot periode, as on the leaderboards.
+func (c *ReportController) branchReportData(kodeUker string, periode time.Time) (*reportData, error) {
+	day := periode.Format("2006-01-02")
+	data := &reportData{
+		Periode:     periode,
+		GeneratedAt: time.Now(),
+		Facts:       map[string][][2]string{},
+		Tables:      map[string][][]string{},
+	}
+
+	if err := c.DB.Where("kode_uker = ?", kodeUker).First(&data.Uker).Error; err != nil {
+		return nil, fmt.Errorf("uker %s: %w", kodeUker, err)
+	}
+	data.Facts["uker"] = [][2]string{
+		{"Kode Uker", data.Uker.KodeUker},
+		{"Nama Uker", data.Uker.NamaUker},
+		{"Main Branch", data.Uker.MainBranch},
+		{"Region", data.Uker.Region},
+		{"Uker Type", data.Uker.UkerType},
+		{"Cluster", data.Uker.Cluster},
+		{"Area", data.Uker.IDArea},
+	}
+
+	leaderboards := &LeaderboardController{DB: c.DB}
+	recovery, err := leaderboards.nextSnapshotPeriode(periode)
+	if err != nil {
+		return nil, err
+	}
+	data.RecoveryPeriode = recovery
+
+	var counts struct {
+		Candidates int64
+		TotalDrop  money.Amount
+		AvgDropPct *float64
+	}
+	err = c.DB.Raw(`SELECT COUNT(*) AS candidates,
+		COALESCE(SUM(CASE WHEN drop_amount > 0 THEN drop_amount END), 0) AS total_drop,
+		ROUND(AVG(drop_pct), 2) AS avg_drop_pct
+		FROM di319 WHERE periode = ? AND branch = ? AND qualifying_rule <> ''`, day, kodeUker).Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// di319 only holds the candidates: the uker's accounts and book balance exist only in a full snapshot,
+	// so without one for the periode those figures are left out rather than printed wrong
+	var book struct {
+		Accounts     int64
+		TotalBalance money.Amount
+	}
+	hasSnapshot := false
+	if c.DB.Migrator().HasTable(&models.DI319Snapshot{}) {
+		err = c.DB.Raw("SELECT EXISTS (SELECT 1 FROM di319_snapshot WHERE periode = ?)", day).Row().Scan(&hasSnapshot)
+		if err == nil && hasSnapshot {
+			err = c.DB.Raw(`SELECT COUNT(*) AS accounts, COALESCE(SUM(balance), 0) AS total_balance
+				FROM di319_snapshot WHERE periode = ? AND branch = ?`, day, kodeUker).Scan(&book).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The uker's own leaderboard entry carries the recovered amount
+	filter := &pagination.Filter{}
+	filter.Add("branch = ?", kodeUker)
+	ukerEntries, err := leaderboards.rankLeaderboard("uker", &leaderboardQuery{
+		Periode: periode, RecoveryPeriode: recovery, Filter: filter, Sort: "total_drop", Desc: true, Limit: 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recovered, rate := "-", "-"
+	if len(ukerEntries) > 0 && ukerEntries[0].Recovered != nil {
+		recovered = formatReportAmount(*ukerEntries[0].Recovered)
+		rate = formatReportPct(ukerEntries[0].RecoveryRate)
+	}
+	if hasSnapshot {
+		data.Facts["candidates"] = [][2]string{
+			{"Accounts", fmt.Sprint(book.Accounts)},
+			{"Total Balance", formatReportAmount(book.TotalBalance)},
+		}
+	}
+	data.Facts["candidates"] = append(data.Facts["candidates"], [][2]string{
+		{"Drop Candidates", fmt.Sprint(counts.Candidates)},
+		{"Total Drop", formatReportAmount(counts.TotalDrop)},
+		{"Average Drop %", formatReportPct(counts.AvgDropPct)},
+		{"Recovered", recovered},
+		{"Recovery Rate %", rate},
+	}...)
+
+	dim := di319StatsDimensions["type"]
+	var types []struct {
+		Name       string
+		Label      string
+		Candidates int64
+		TotalDrop  money.Amount
+	}
+	err = c.DB.Raw(`SELECT `+dim.Key+` AS name, COALESCE(`+dim.Label+`, '') AS label, COUNT(*) AS candidates,
+		COALESCE(SUM(CASE WHEN d.drop_amount > 0 THEN d.drop_amount END), 0) AS total_drop
+		FROM (SELECT * FROM di319 WHERE periode = ? AND branch = ? AND qualifying_rule <> '') d`+dim.Joins+`
+		GROUP BY `+dim.Key+` ORDER BY total_drop DESC, name`, day, kodeUker).Scan(&types).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		data.Tables["types"] = append(data.Tables["types"], []string{
+			t.Name, t.Label, fmt.Sprint(t.Candidates), formatReportAmount(t.TotalDrop),
+		})
+	}
+
+	var drops []models.DI319
+	err = c.DB.Where("periode = ? AND branch = ? AND qualifying_rule <> ''", day, kodeUker).
+		Order("drop_amount IS NULL, drop_amount DESC, norek").Limit(reportTopDrops).Find(&drops).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range drops {
+		drop := "-"
+		if d.DropAmount != nil {
+			drop = formatReportAmount(*d.DropAmount)
+		}
+		data.Tables["top_drops"] = append(data.Tables["top_drops"], []string{
+			d.NoRek, d.Nama, d.Type, d.PNPengelola, formatReportAmount(d.Balance), drop, formatReportPct(d.DropPct),
+		})
+	}
+
+	officers, err := leaderboards.rankLeaderboard("rmft", &leaderboardQuery{
+		Periode: periode, RecoveryPeriode: recovery, Filter: filter, Sort: "total_drop", Desc: true,
+		Limit: pagination.MaxPageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range officers {
+		recovered := "-"
+		if o.Recovered != nil {
+			recovered = formatReportAmount(*o.Recovered)
+		}
+		data.Tables["rmft"] = append(data.Tables["rmft"], []string{
+			o.Name, o.Label, fmt.Sprint(o.Candidates), formatReportAmount(o.TotalDrop),
+			fmt.Sprint(o.FollowedUp), recovered, formatReportPct(o.RecoveryRate),
+		})
+	}
+
+	// Recovery status of every candidate in the recovery periode
+	status := "'Awaiting next periode'"
+	from := " FROM (SELECT * FROM di319 WHERE periode = ? AND branch = ? AND qualifying_rule <> '') d"
+	args := []interface{}{day, kodeUker}
+	if recovery != nil {
+		status = `CASE WHEN f.norek IS NULL THEN 'Closed' WHEN f.flagged = 1 THEN 'Still flagged' ELSE 'Recovered' END`
+		from += " LEFT JOIN (SELECT norek, MAX(qualifying_rule <> '') AS flagged FROM di319_snapshot WHERE periode = ? GROUP BY norek) f ON f.norek = d.norek"
+		args = append(args, recovery.Format("2006-01-02"))
+	}
+	var statuses []struct {
+		Status     string
+		Accounts   int64
+		DropAmount money.Amount
+	}
+	err = c.DB.Raw(`SELECT `+status+` AS status, COUNT(*) AS accounts,
+		COALESCE(SUM(CASE WHEN d.drop_amount > 0 THEN d.drop_amount END), 0) AS drop_amount`+from+`
+		GROUP BY 1 ORDER BY 1`, args...).Scan(&statuses).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statuses {
+		data.Tables["recovery"] = append(data.Tables["recovery"], []string{
+			s.Status, fmt.Sprint(s.Accounts), formatReportAmount(s.DropAmount),
+		})
+	}
+
+	return data, nil
+}
+
+var errInvalidReportPeriode = errors.New("invalid periode (expected yyyy-mm or yyyy-mm-dd)")
+
+// resolveReportPeriode - The latest DI319 periode within raw (a month or a day), or overall when raw is empty.
+// Returns nil when nothing matches.
+func (c *ReportController) resolveReportPeriode(raw string) (*time.Time, error) {
+	if raw == "" {
+		var latest sql.NullTime
+		if err := c.DB.Raw("SELECT MAX(periode) FROM di319").Row().Scan(&latest); err != nil || !latest.Valid {
+			return nil, err
+		}
+		return &latest.Time, nil
+	}
+	start, end, err := parseComparePeriode("periode", raw)
+	if err != nil {
+		return nil, errInvalidReportPeriode
+	}
+	return (&DI319ImportController{DB: c.DB}).latestPeriodeBetween("di319", start, end)
+}
+
+// GetAll - List generated reports (GET /api/reports?uker=&region=&batch_id=&status=&periode=)
+func (c *ReportController) GetAll(ctx *fiber.Ctx) error {
+	params, err := pagination.Parse(ctx, reportListSpec)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := c.applyReportScope(ctx, params.Filter); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load data scope",
+		})
+	}
+
+	result, err := pagination.List[models.Report](c.DB, c.DB.Model(&models.Report{}), params)
+	if err == pagination.ErrInvalidCursor {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return pagination.Respond(ctx, params, result)
+}
+
+// GetByID - Get one report's status
+func (c *ReportController) GetByID(ctx *fiber.Ctx) error {
+	report, status, err := c.findReport(ctx)
+	if err != nil {
+		return ctx.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return ctx.JSON(report)
+}
+
+// Download - Send a generated report's PDF (GET /api/reports/:id/download)
+func (c *ReportController) Download(ctx *fiber.Ctx) error {
+	report, status, err := c.findReport(ctx)
+	if err != nil {
+		return ctx.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if report.Status != models.ReportDone {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":  "Report is not ready",
+			"status": report.Status,
+		})
+	}
+	if _, err := os.Stat(report.Path); err != nil {
+		return ctx.Status(fiber.StatusGone).JSON(fiber.Map{
+			"error": "Report file is no longer available",
+		})
+	}
+
+	userID, username := auditUser(ctx)
+	writeAuditLog(c.DB, userID, username, "download", "report", fmt.Sprintf("id=%d uker=%s", report.ID, report.Uker), 1)
+
+	ctx.Set(fiber.HeaderContentType, "application/pdf")
+	return ctx.Download(report.Path, report.Filename)
+}
+
+// Delete - Remove a report and its file (admin only)
+func (c *ReportController) Delete(ctx *fiber.Ctx) error {
+	report, status, err := c.findReport(ctx)
+	if err != nil {
+		return ctx.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if report.Status == models.ReportQueued || report.Status == models.ReportRunning {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Report is still being generated",
+		})
+	}
+	if err := c.DB.Delete(&models.Report{}, report.ID).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete report",
+		})
+	}
+	if report.Path != "" {
+		os.Remove(report.Path)
+	}
+	return ctx.JSON(fiber.Map{
+		"message": "Report deleted successfully",
+	})
+}
+
+// findReport - Load the report named by :id, limited to the user's data scope.
+// On failure also returns the HTTP status to answer with.
+func (c *ReportController) findReport(ctx *fiber.Ctx) (*models.Report, int, error) {
+	filter := &pagination.Filter{}
+	filter.Add("id = ?", ctx.Params("id"))
+	if err := c.applyReportScope(ctx, filter); err != nil {
+		return nil, fiber.StatusInternalServerError, errors.New("Failed to load data scope")
+	}
+
+	var report models.Report
+	if err := filter.Apply(c.DB).First(&report).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.StatusNotFound, errors.New("Report not found")
+		}
+		return nil, fiber.StatusInternalServerError, err
+	}
+	return &report, fiber.StatusOK, nil
+}
+
+// applyReportScope - Restrict reports to ukers in the user's scope (the uker itself or its main branch)
+func (c *ReportController) applyReportScope(ctx *fiber.Ctx, filter *pagination.Filter) error {
+	scope, err := userDataScope(c.DB, ctx)
+	if err != nil || len(scope) == 0 {
+		return err
+	}
+	filter.Add("(uker IN ? OR uker IN (SELECT kode_uker FROM uker WHERE main_branch IN ?))", scope, scope)
+	return nil
+}
+
+// FailInterruptedReports - Reports still queued or running at startup were cut off by a restart
+func FailInterruptedReports(db *gorm.DB) error {
+	return db.Model(&models.Report{}).
+		Where("status IN ?", []string{models.ReportQueued, models.ReportRunning}).
+		Updates(map[string]interface{}{"status": models.ReportFailed, "error": "interrupted by a server restart"}).Error
+}
@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"pipeline-backend/models"
+	"pipeline-backend/money"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// reportTemplate - Layout of one report: text/template strings over reportData for the title,
+// subtitle and footer, then the sections in order
+type reportTemplate struct {
+	Name     string
+	Title    string
+	Subtitle string
+	Footer   string
+	Sections []reportSection
+}
+
+// reportSection - A block of the report, filled from reportData.Facts (label / value pairs)
+// or reportData.Tables (rows matching Columns) under the Source key
+type reportSection struct {
+	Title   string
+	Source  string
+	Columns []reportColumn // nil for a facts section
+	Empty   string         // shown when the table has no rows
+}
+
+// reportColumn - Table column; widths are relative weights of the page width
+type reportColumn struct {
+	Title  string
+	Weight float64
+	Align  string // L, C or R
+}
+
+// reportData - Everything a template can show
+type reportData struct {
+	Uker            models.Uker
+	Periode         time.Time
+	RecoveryPeriode *time.Time
+	GeneratedAt     time.Time
+	Facts           map[string][][2]string
+	Tables          map[string][][]string
+}
+
+// reportTemplates - Available report layouts by name
+var reportTemplates = map[string]*reportTemplate{
+	"branch_monthly": {
+		Name:     "branch_monthly",
+		Title:    "Monthly DI319 Report - {{.Uker.NamaUker}} ({{.Uker.KodeUker}})",
+		Subtitle: `Periode {{.Periode.Format "2006-01-02"}}{{with .RecoveryPeriode}}, recovery measured at {{.Format "2006-01-02"}}{{else}}, recovery not yet measurable{{end}}`,
+		Footer:   `Pipeline - generated {{.GeneratedAt.Format "2006-01-02 15:04"}}`,
+		Sections: []reportSection{
+			{Title: "Uker", Source: "uker"},
+			{Title: "Drop Candidates", Source: "candidates"},
+			{
+				Title:  "Candidates by Product Type",
+				Source: "types",
+				Columns: []reportColumn{
+					{"Type", 1, "L"}, {"Product", 3, "L"}, {"Candidates", 1.2, "R"}, {"Total Drop", 2, "R"},
+				},
+				Empty: "No drop candidates in this periode.",
+			},
+			{
+				Title:  "Top Drops",
+				Source: "top_drops",
+				Columns: []reportColumn{
+					{"No Rekening", 2, "L"}, {"Nama", 3, "L"}, {"Type", 0.9, "L"}, {"PN", 1.3, "L"},
+					{"Balance", 1.8, "R"}, {"Drop", 1.8, "R"}, {"Drop %", 1, "R"},
+				},
+				Empty: "No drop candidates in this periode.",
+			},
+			{
+				Title:  "RMFT Breakdown",
+				Source: "rmft",
+				Columns: []reportColumn{
+					{"PN", 1.3, "L"}, {"Nama", 3, "L"}, {"Candidates", 1.2, "R"}, {"Total Drop", 1.8, "R"},
+					{"Followed Up", 1.2, "R"}, {"Recovered", 1.8, "R"}, {"Rate %", 1, "R"},
+				},
+				Empty: "No drop candidates in this periode.",
+			},
+			{
+				Title:  "Recovery Status",
+				Source: "recovery",
+				Columns: []reportColumn{
+					{"Status", 3, "L"}, {"Accounts", 1.2, "R"}, {"Drop Amount", 2, "R"},
+				},
+				Empty: "No drop candidates in this periode.",
+			},
+		},
+	},
+}
+
+// renderReportPDF - Lay out data with tpl as an A4 PDF
+func renderReportPDF(tpl *reportTemplate, data *reportData, w io.Writer) error {
+	title, err := executeReportText(tpl.Name+".title", tpl.Title, data)
+	if err != nil {
+		return err
+	}
+	subtitle, err := executeReportText(tpl.Name+".subtitle", tpl.Subtitle, data)
+	if err != nil {
+		return err
+	}
+	footer, err := executeReportText(tpl.Name+".footer", tpl.Footer, data)
+	if err != nil {
+		return err
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("") // core fonts are cp1252
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("Pipeline", true)
+	pdf.SetMargins(12, 12, 12)
+	pdf.SetAutoPageBreak(true, 16)
+	pdf.AliasNbPages("")
+
+	pageWidth, pageHeight := pdf.GetPageSize()
+	width := pageWidth - 24
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 7)
+		pdf.SetTextColor(110, 110, 110)
+		pdf.CellFormat(width/2, 5, tr(footer), "", 0, "L", false, 0, "")
+		pdf.CellFormat(width/2, 5, fmt.Sprintf("Page %d/{nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.MultiCell(width, 7, tr(title), "", "L", false)
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(80, 80, 80)
+	pdf.MultiCell(width, 5, tr(subtitle), "", "L", false)
+	pdf.Ln(3)
+
+	// Start a new page when fewer than need mm are left
+	ensure := func(need float64) bool {
+		if pdf.GetY()+need > pageHeight-16 {
+			pdf.AddPage()
+			return true
+		}
+		return false
+	}
+
+	for _, section := range tpl.Sections {
+		ensure(20)
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.SetTextColor(0, 51, 102)
+		pdf.CellFormat(width, 7, tr(section.Title), "B", 1, "L", false, 0, "")
+		pdf.Ln(1)
+		pdf.SetTextColor(0, 0, 0)
+
+		if section.Columns == nil {
+			for _, fact := range data.Facts[section.Source] {
+				ensure(5)
+				pdf.SetFont("Helvetica", "B", 9)
+				pdf.CellFormat(width*0.35, 5, tr(fact[0]), "", 0, "L", false, 0, "")
+				pdf.SetFont("Helvetica", "", 9)
+				pdf.CellFormat(width*0.65, 5, tr(fitReportText(pdf, fact[1], width*0.65)), "", 1, "L", false, 0, "")
+			}
+			pdf.Ln(3)
+			continue
+		}
+
+		var total float64
+		for _, col := range section.Columns {
+			total += col.Weight
+		}
+		widths := make([]float64, len(section.Columns))
+		for i, col := range section.Columns {
+			widths[i] = width * col.Weight / total
+		}
+		header := func() {
+			pdf.SetFont("Helvetica", "B", 8)
+			pdf.SetFillColor(225, 232, 240)
+			for i, col := range section.Columns {
+				pdf.CellFormat(widths[i], 6, tr(col.Title), "1", 0, col.Align, true, 0, "")
+			}
+			pdf.Ln(-1)
+			pdf.SetFont("Helvetica", "", 8)
+		}
+		header()
+
+		rows := data.Tables[section.Source]
+		if len(rows) == 0 {
+			pdf.SetFont("Helvetica", "I", 8)
+			pdf.CellFormat(width, 6, tr(section.Empty), "1", 1, "L", false, 0, "")
+		}
+		for _, row := range rows {
+			if ensure(5.5) {
+				header()
+			}
+			for i, col := range section.Columns {
+				value := ""
+				if i < len(row) {
+					value = fitReportText(pdf, row[i], widths[i]-2)
+				}
+				pdf.CellFormat(widths[i], 5.5, tr(value), "1", 0, col.Align, false, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+		pdf.Ln(4)
+	}
+
+	return pdf.Output(w)
+}
+
+// executeReportText - Run one of the template's text templates over data
+func executeReportText(name, text string, data *reportData) (string, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("report template %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("report template %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// fitReportText - Shorten s with an ellipsis until it fits width at the current font
+func fitReportText(pdf *gofpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// formatReportAmount - 1.234.567,89 (Indonesian grouping)
+func formatReportAmount(a money.Amount) string {
+	plain := a.String()
+	sign := ""
+	if strings.HasPrefix(plain, "-") {
+		sign, plain = "-", plain[1:]
+	}
+	whole, frac, _ := strings.Cut(plain, ".")
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(digit)
+	}
+	return sign + grouped.String() + "," + frac
+}
+
+// formatReportPct - 12,34, or - when unknown
+func formatReportPct(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return strings.Replace(strconv.FormatFloat(*v, 'f', 2, 64), ".", ",", 1)
+}
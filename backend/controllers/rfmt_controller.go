@@ -2,7 +2,7 @@ package controllers
 
 import (
 	"pipeline-backend/models"
-	"strconv"
+	"pipeline-backend/pagination"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -16,43 +16,55 @@ func NewRFMTController(db *gorm.DB) *RFMTController {
 	return &RFMTController{DB: db}
 }
 
+// rfmtListSpec - Paging, sorting and filtering of GET /api/rfmts (legacy keys: total/page/limit)
+var rfmtListSpec = pagination.Spec{
+	Table: "rfmts",
+	Sorts: map[string]pagination.Sort{
+		"created_at":   {Column: "created_at"},
+		"pn":           {Column: "pn"},
+		"nama_lengkap": {Column: "nama_lengkap"},
+		"kanca":        {Column: "kanca"},
+		"id":           {},
+	},
+	DefaultSort: "created_at",
+	DefaultDesc: true,
+	Filters: []pagination.FilterField{
+		{Param: "pn", Column: "pn", Kind: pagination.FilterEqual},
+	},
+	SearchColumns: []string{"pn", "nama_lengkap", "jg", "kanca"},
+	Legacy: func(m pagination.Meta) fiber.Map {
+		total := int64(0)
+		if m.Total != nil {
+			total = *m.Total
+		}
+		return fiber.Map{
+			"total":          total,
+			"page":           m.Page,
+			"limit":          m.PageSize,
+			"total_estimate": m.TotalIsEstimate,
+			"next_cursor":    m.NextCursor,
+			"has_more":       m.HasMore,
+		}
+	},
+}
+
 // GetAll - Get all RFMTs with pagination and filters
 func (c *RFMTController) GetAll(ctx *fiber.Ctx) error {
-	page, _ := strconv.Atoi(ctx.Query("page", "1"))
-	limit, _ := strconv.Atoi(ctx.Query("limit", "10"))
-	search := ctx.Query("search", "")
-	pn := ctx.Query("pn", "")
-
-	offset := (page - 1) * limit
-
-	var rfmts []models.RFMT
-	var total int64
-
-	query := c.DB.Model(&models.RFMT{}).Preload("UkerRelation")
-
-	// Filter by PN if provided
-	if pn != "" {
-		query = query.Where("pn = ?", pn)
+	params, err := pagination.Parse(ctx, rfmtListSpec)
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	// Search filter
-	if search != "" {
-		query = query.Where("pn LIKE ? OR nama_lengkap LIKE ? OR jg LIKE ? OR kanca LIKE ?",
-			"%"+search+"%", "%"+search+"%", "%"+search+"%", "%"+search+"%")
+	query := c.DB.Model(&models.RFMT{}).Preload("UkerRelation")
+	result, err := pagination.List[models.RFMT](c.DB, query, params)
+	if err == pagination.ErrInvalidCursor {
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	query.Count(&total)
-
-	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&rfmts).Error; err != nil {
+	if err != nil {
 		return ctx.Status(500).JSON(fiber.Map{"error": "Failed to fetch RFMTs"})
 	}
 
-	return ctx.JSON(fiber.Map{
-		"data":  rfmts,
-		"total": total,
-		"page":  page,
-		"limit": limit,
-	})
+	return pagination.Respond(ctx, params, result)
 }
 
 // GetByID - Get single RFMT by ID
@@ -25,10 +25,10 @@ var (
 
 // RFMTImportCSV handles CSV file upload and imports RFMT data
 func (c *RFMTController) ImportCSV(ctx *fiber.Ctx) error {
-	// Get uploaded file
-	file, err := ctx.FormFile("file")
+	// Get uploaded file (multipart "file" or a completed resumable "upload_id")
+	file, err := resolveImportSource(ctx, c.DB)
 	if err != nil {
-		return ctx.Status(400).JSON(fiber.Map{"error": "No file uploaded"})
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	// Open file
@@ -0,0 +1,375 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"pipeline-backend/models"
+	"pipeline-backend/pagination"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type ScheduledJobController struct {
+	DB        *gorm.DB
+	Scheduler *Scheduler
+}
+
+func NewScheduledJobController(db *gorm.DB) *ScheduledJobController {
+	return &ScheduledJobController{DB: db, Scheduler: NewScheduler(db)}
+}
+
+// scheduledJobListSpec - Paging, sorting and filtering of GET /api/scheduled-jobs
+var scheduledJobListSpec = pagination.Spec{
+	Table: "scheduled_jobs",
+	Sorts: map[string]pagination.Sort{
+		"id":          {},
+		"name":        {Column: "name"},
+		"kind":        {Column: "kind"},
+		"next_run_at": {Column: "next_run_at", Nullable: true},
+		"last_run_at": {Column: "last_run_at", Nullable: true},
+	},
+	DefaultSort: "name",
+	Filters: []pagination.FilterField{
+		{Param: "kind", Column: "kind", Kind: pagination.FilterIn},
+		{Param: "last_status", Column: "last_status", Kind: pagination.FilterIn},
+	},
+	SearchColumns: []string{"name"},
+}
+
+// scheduledJobRunListSpec - Paging and filtering of GET /api/scheduled-jobs/:id/runs
+var scheduledJobRunListSpec = pagination.Spec{
+	Table: "scheduled_job_runs",
+	Sorts: map[string]pagination.Sort{
+		"id": {},
+	},
+	DefaultSort: "id",
+	DefaultDesc: true,
+	Filters: []pagination.FilterField{
+		{Param: "status", Column: "status", Kind: pagination.FilterIn},
+		{Param: "trigger", Column: "run_trigger", Kind: pagination.FilterIn},
+		{Param: "started_at", Column: "started_at", Kind: pagination.FilterDateRange},
+	},
+}
+
+// scheduledJobRequest - Body of Create / Update. Params is the kind's JSON object:
+//   - report: {"region": "R1"} or {"uker": "10272"}, optional "periode", "template"
+//   - export: {"dataset": "di319|stats", "format": "csv|xlsx", "query": "branch=10272&rule=...", "latest_periode": true}
+//   - recovery: {"by": "uker|main_branch|region|rmft", "periodes": 3}
+//   - retention: {"di319_months": 24, "snapshot_months": 12, "audit_log_days": 365, "report_days": 90, "job_run_days": 90}
+type scheduledJobRequest struct {
+	Name              string          `json:"name"`
+	Kind              string          `json:"kind"`
+	Cron              string          `json:"cron"`
+	Params            json.RawMessage `json:"params"`
+	Enabled           *bool           `json:"enabled"`             // default true
+	MaxRetries        *int            `json:"max_retries"`         // default 3, at most 10
+	RetryDelayMinutes *int            `json:"retry_delay_minutes"` // default 5, at most 1440
+}
+
+// apply - Validate the request and copy it onto job, scheduling its next run
+func (r *scheduledJobRequest) apply(job *models.ScheduledJob) error {
+	r.Name, r.Cron = strings.TrimSpace(r.Name), strings.TrimSpace(r.Cron)
+	if r.Name == "" || len(r.Name) > 100 {
+		return errors.New("name is required (at most 100 characters)")
+	}
+	kind, ok := scheduledJobKinds[r.Kind]
+	if !ok {
+		return fmt.Errorf("invalid kind: %s (expected report, export, recovery or retention)", r.Kind)
+	}
+	next, err := nextJobRun(r.Cron, time.Now())
+	if err != nil {
+		return err
+	}
+
+	params := ""
+	if len(r.Params) > 0 && string(r.Params) != "null" {
+		if r.Params[0] != '{' {
+			return errors.New("params must be a JSON object")
+		}
+		params = string(r.Params)
+	}
+	if err := kind.Validate(params); err != nil {
+		return err
+	}
+
+	job.Name, job.Kind, job.Cron, job.Params = r.Name, r.Kind, r.Cron, params
+	job.Enabled, job.MaxRetries, job.RetryDelayMinutes = true, 3, 5
+	if r.Enabled != nil {
+		job.Enabled = *r.Enabled
+	}
+	if r.MaxRetries != nil {
+		if *r.MaxRetries < 0 || *r.MaxRetries > 10 {
+			return errors.New("max_retries must be between 0 and 10")
+		}
+		job.MaxRetries = *r.MaxRetries
+	}
+	if r.RetryDelayMinutes != nil {
+		if *r.RetryDelayMinutes < 1 || *r.RetryDelayMinutes > 1440 {
+			return errors.New("retry_delay_minutes must be between 1 and 1440")
+		}
+		job.RetryDelayMinutes = *r.RetryDelayMinutes
+	}
+
+	job.RetryAttempt = 0
+	job.NextRunAt = nil
+	if job.Enabled {
+		job.NextRunAt = &next
+	}
+	return nil
+}
+
+// GetAll - List scheduled jobs (admin only)
+func (c *ScheduledJobController) GetAll(ctx *fiber.Ctx) error {
+	params, err := pagination.Parse(ctx, scheduledJobListSpec)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	result, err := pagination.List[models.ScheduledJob](c.DB, c.DB.Model(&models.ScheduledJob{}), params)
+	if err == pagination.ErrInvalidCursor {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return pagination.Respond(ctx, params, result)
+}
+
+// GetByID - Get scheduled job by ID (admin only)
+func (c *ScheduledJobController) GetByID(ctx *fiber.Ctx) error {
+	var job models.ScheduledJob
+
+	if err := c.DB.First(&job, ctx.Params("id")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Scheduled job not found",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"data": job,
+	})
+}
+
+// Create - Create new scheduled job (admin only)
+func (c *ScheduledJobController) Create(ctx *fiber.Ctx) error {
+	var req scheduledJobRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var job models.ScheduledJob
+	if err := req.apply(&job); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	job.CreatedBy, _ = auditUser(ctx)
+
+	// Check if name already exists
+	var existing models.ScheduledJob
+	if err := c.DB.Where("name = ?", job.Name).First(&existing).Error; err == nil {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Scheduled job with this name already exists",
+		})
+	}
+
+	if err := c.DB.Create(&job).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Scheduled job created successfully",
+		"data":    job,
+	})
+}
+
+// Update - Replace a scheduled job's definition; its next run is rescheduled (admin only)
+func (c *ScheduledJobController) Update(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	var job models.ScheduledJob
+
+	if err := c.DB.First(&job, id).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Scheduled job not found",
+		})
+	}
+
+	var req scheduledJobRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if err := req.apply(&job); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Check if new name already exists (except current record)
+	var existing models.ScheduledJob
+	if err := c.DB.Where("name = ? AND id != ?", job.Name, id).First(&existing).Error; err == nil {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Scheduled job with this name already exists",
+		})
+	}
+
+	if err := c.DB.Save(&job).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "Scheduled job updated successfully",
+		"data":    job,
+	})
+}
+
+// Delete - Delete a scheduled job with its run history and output files (admin only)
+func (c *ScheduledJobController) Delete(ctx *fiber.Ctx) error {
+	var job models.ScheduledJob
+
+	if err := c.DB.First(&job, ctx.Params("id")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Scheduled job not found",
+		})
+	}
+
+	schedulerMu.Lock()
+	running := schedulerRunning[job.ID]
+	schedulerMu.Unlock()
+	if running {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Scheduled job is running",
+		})
+	}
+
+	var outputs []string
+	c.DB.Model(&models.ScheduledJobRun{}).Where("job_id = ? AND output <> ''", job.ID).Pluck("output", &outputs)
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("job_id = ?", job.ID).Delete(&models.ScheduledJobRun{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&job).Error
+	})
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	for _, path := range outputs {
+		os.Remove(path)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "Scheduled job deleted successfully",
+	})
+}
+
+// Run - Start a job now, outside its timetable (POST /api/scheduled-jobs/:id/run, admin only).
+// Returns 202 with the run; follow it in GET /api/scheduled-jobs/:id/runs.
+func (c *ScheduledJobController) Run(ctx *fiber.Ctx) error {
+	var job models.ScheduledJob
+
+	if err := c.DB.First(&job, ctx.Params("id")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Scheduled job not found",
+		})
+	}
+
+	run, err := c.Scheduler.startRun(job, "manual", 1)
+	if err == errJobRunning {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Scheduled job is already running",
+		})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	go c.Scheduler.finishRun(job, run)
+
+	userID, username := auditUser(ctx)
+	writeAuditLog(c.DB, userID, username, "run", "scheduled_job", fmt.Sprintf("id=%d name=%s", job.ID, job.Name), 0)
+
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Scheduled job started",
+		"data":    run,
+	})
+}
+
+// GetRuns - Run history of a job, newest first (GET /api/scheduled-jobs/:id/runs?status=&trigger=, admin only)
+func (c *ScheduledJobController) GetRuns(ctx *fiber.Ctx) error {
+	var job models.ScheduledJob
+	if err := c.DB.First(&job, ctx.Params("id")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Scheduled job not found",
+		})
+	}
+
+	params, err := pagination.Parse(ctx, scheduledJobRunListSpec)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	params.Filter.Add("job_id = ?", job.ID)
+
+	result, err := pagination.List[models.ScheduledJobRun](c.DB, c.DB.Model(&models.ScheduledJobRun{}), params)
+	if err == pagination.ErrInvalidCursor {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return pagination.Respond(ctx, params, result)
+}
+
+// DownloadRun - Send a run's output file (GET /api/scheduled-jobs/runs/:runId/download, admin only)
+func (c *ScheduledJobController) DownloadRun(ctx *fiber.Ctx) error {
+	var run models.ScheduledJobRun
+	if err := c.DB.First(&run, ctx.Params("runId")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Job run not found",
+		})
+	}
+	if run.Output == "" {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Job run has no output file",
+		})
+	}
+	if _, err := os.Stat(run.Output); err != nil {
+		return ctx.Status(fiber.StatusGone).JSON(fiber.Map{
+			"error": "Output file is no longer available",
+		})
+	}
+
+	userID, username := auditUser(ctx)
+	writeAuditLog(c.DB, userID, username, "download", "scheduled_job_run", fmt.Sprintf("id=%d job_id=%d", run.ID, run.JobID), run.Rows)
+
+	return ctx.Download(run.Output, run.OutputName)
+}
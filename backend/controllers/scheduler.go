@@ -0,0 +1,320 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"pipeline-backend/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/robfig/cron/v3"
+	"github.com/valyala/fasthttp"
+	"gorm.io/gorm"
+)
+
+// Scheduler - Runs the jobs of scheduled_jobs when they are due. Each job's next_run_at is claimed with a
+// conditional update, so several backend instances can share the table without running a job twice.
+type Scheduler struct {
+	DB      *gorm.DB
+	Dir     string
+	Reports *ReportController
+}
+
+func NewScheduler(db *gorm.DB) *Scheduler {
+	dir := os.Getenv("SCHEDULER_DIR")
+	if dir == "" {
+		dir = "scheduled"
+	}
+	return &Scheduler{DB: db, Dir: dir, Reports: NewReportController(db)}
+}
+
+// schedulerInstance - Identifies this process on the runs it executes
+var schedulerInstance = newSchedulerInstance()
+
+func newSchedulerInstance() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano())
+}
+
+// A running run's heartbeat is refreshed every jobRunHeartbeat; one silent for jobRunStaleAfter belongs to an
+// instance that stopped and is failed by whichever instance notices it.
+const (
+	jobRunHeartbeat  = time.Minute
+	jobRunStaleAfter = 5 * time.Minute
+)
+
+// schedulerRunning - Jobs running in this process; a job never overlaps itself
+var (
+	schedulerMu      sync.Mutex
+	schedulerRunning = map[uint]bool{}
+)
+
+var errJobRunning = errors.New("job is already running")
+
+// jobCronParser - Standard 5-field expressions plus descriptors (@daily, @every 1h); CRON_TZ= sets the zone
+var jobCronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// nextJobRun - The first time expr fires after t
+func nextJobRun(expr string, t time.Time) (time.Time, error) {
+	schedule, err := jobCronParser.Parse(expr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cron: %v", err)
+	}
+	return schedule.Next(t), nil
+}
+
+// jobResult - What a run produced
+type jobResult struct {
+	Message    string
+	Output     string // file path, "" when the run keeps no file
+	OutputName string
+	Rows       int64
+}
+
+// scheduledJobKind - What a job can do. Validate checks the params when a job is saved; Run does the work,
+// writing any output file below dir.
+type scheduledJobKind struct {
+	Validate func(params string) error
+	Run      func(s *Scheduler, job models.ScheduledJob, dir string) (*jobResult, error)
+}
+
+// StartScheduler - Fail runs abandoned by a stopped instance and poll for due jobs every SCHEDULER_POLL_SECONDS
+// (default 30). SCHEDULER_ENABLED=false leaves scheduling to another instance; jobs can still be run by hand.
+func StartScheduler(db *gorm.DB) {
+	failStaleJobRuns(db, time.Now())
+
+	if os.Getenv("SCHEDULER_ENABLED") == "false" {
+		log.Println("⏸️  Scheduler disabled (SCHEDULER_ENABLED=false)")
+		return
+	}
+
+	s := NewScheduler(db)
+	interval := time.Duration(clampInt(envInt("SCHEDULER_POLL_SECONDS", 30), 5, 3600)) * time.Second
+	log.Printf("⏰ Scheduler started (polling every %v)", interval)
+	go func() {
+		for {
+			s.tick(time.Now())
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// failStaleJobRuns - Fail the running runs of other instances whose heartbeat stopped. Runs recorded before
+// heartbeats existed fall back to started_at.
+func failStaleJobRuns(db *gorm.DB, now time.Time) {
+	result := db.Model(&models.ScheduledJobRun{}).
+		Where("status = ? AND instance <> ? AND COALESCE(heartbeat_at, started_at) < ?",
+			models.JobRunRunning, schedulerInstance, now.Add(-jobRunStaleAfter)).
+		Updates(map[string]interface{}{"status": models.JobRunFailed, "message": "interrupted: the instance running it stopped", "finished_at": now})
+	if result.Error != nil {
+		log.Println("⚠️  Failed to mark interrupted job runs:", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("⚠️  Marked %d interrupted job run(s) as failed", result.RowsAffected)
+	}
+}
+
+// tick - Fail abandoned runs, then start every enabled job whose next_run_at has passed
+func (s *Scheduler) tick(now time.Time) {
+	failStaleJobRuns(s.DB, now)
+
+	var due []models.ScheduledJob
+	if err := s.DB.Where("enabled = ? AND next_run_at <= ?", true, now).Order("next_run_at").Find(&due).Error; err != nil {
+		log.Println("⚠️  Scheduler: failed to load due jobs:", err)
+		return
+	}
+
+	for _, job := range due {
+		next, err := nextJobRun(job.Cron, now)
+		if err != nil {
+			log.Printf("⚠️  Scheduler: job %d (%s): %v", job.ID, job.Name, err)
+			s.DB.Model(&models.ScheduledJob{}).Where("id = ?", job.ID).
+				Updates(map[string]interface{}{"next_run_at": nil, "last_status": models.JobRunFailed})
+			continue
+		}
+
+		// Claim the run: only the instance that moves next_run_at on runs it
+		claim := s.DB.Model(&models.ScheduledJob{}).Where("id = ? AND next_run_at = ?", job.ID, job.NextRunAt).
+			Update("next_run_at", next)
+		if claim.Error != nil || claim.RowsAffected == 0 {
+			continue
+		}
+		job.NextRunAt = &next
+
+		trigger, attempt := "schedule", 1
+		if job.RetryAttempt > 0 {
+			trigger, attempt = "retry", job.RetryAttempt+1
+		}
+		run, err := s.startRun(job, trigger, attempt)
+		if err != nil {
+			log.Printf("⚠️  Scheduler: job %d (%s) not started: %v", job.ID, job.Name, err)
+			continue
+		}
+		go s.finishRun(job, run)
+	}
+}
+
+// startRun - Record a new run of job and mark the job running in this process
+func (s *Scheduler) startRun(job models.ScheduledJob, trigger string, attempt int) (*models.ScheduledJobRun, error) {
+	schedulerMu.Lock()
+	defer schedulerMu.Unlock()
+	if schedulerRunning[job.ID] {
+		return nil, errJobRunning
+	}
+
+	now := time.Now()
+	run := models.ScheduledJobRun{
+		JobID:       job.ID,
+		Trigger:     trigger,
+		Attempt:     attempt,
+		Status:      models.JobRunRunning,
+		Instance:    schedulerInstance,
+		HeartbeatAt: &now,
+		StartedAt:   now,
+	}
+	if err := s.DB.Create(&run).Error; err != nil {
+		return nil, err
+	}
+	schedulerRunning[job.ID] = true
+	return &run, nil
+}
+
+// finishRun - Execute a started run and record its outcome. A failed scheduled run is retried after
+// retry_delay_minutes x attempt, up to max_retries extra attempts (manual runs are not retried).
+func (s *Scheduler) finishRun(job models.ScheduledJob, run *models.ScheduledJobRun) {
+	defer func() {
+		schedulerMu.Lock()
+		delete(schedulerRunning, job.ID)
+		schedulerMu.Unlock()
+	}()
+
+	stop := make(chan struct{})
+	go s.heartbeat(run.ID, stop)
+	result, err := s.execute(job)
+	close(stop)
+	finished := time.Now()
+
+	runUpdates := map[string]interface{}{"finished_at": finished}
+	jobUpdates := map[string]interface{}{"last_run_at": run.StartedAt}
+	if err != nil {
+		log.Printf("❌ Job %d (%s) attempt %d failed: %v", job.ID, job.Name, run.Attempt, err)
+		runUpdates["status"] = models.JobRunFailed
+		runUpdates["message"] = err.Error()
+		jobUpdates["last_status"] = models.JobRunFailed
+
+		if run.Trigger != "manual" {
+			if run.Attempt <= job.MaxRetries {
+				retryAt := finished.Add(time.Duration(job.RetryDelayMinutes*run.Attempt) * time.Minute)
+				if job.NextRunAt == nil || retryAt.Before(*job.NextRunAt) {
+					jobUpdates["next_run_at"] = retryAt
+				}
+				jobUpdates["retry_attempt"] = run.Attempt
+			} else {
+				jobUpdates["retry_attempt"] = 0
+			}
+		}
+	} else {
+		log.Printf("✅ Job %d (%s) done in %v: %s", job.ID, job.Name, finished.Sub(run.StartedAt), result.Message)
+		runUpdates["status"] = models.JobRunSuccess
+		runUpdates["message"] = result.Message
+		runUpdates["output"] = result.Output
+		runUpdates["output_name"] = result.OutputName
+		runUpdates["rows"] = result.Rows
+		jobUpdates["last_status"] = models.JobRunSuccess
+		jobUpdates["retry_attempt"] = 0
+	}
+
+	if err := s.DB.Model(&models.ScheduledJobRun{}).Where("id = ?", run.ID).Updates(runUpdates).Error; err != nil {
+		log.Printf("⚠️  Failed to record run %d of job %d: %v", run.ID, job.ID, err)
+	}
+	if err := s.DB.Model(&models.ScheduledJob{}).Where("id = ?", job.ID).Updates(jobUpdates).Error; err != nil {
+		log.Printf("⚠️  Failed to update job %d: %v", job.ID, err)
+	}
+}
+
+// heartbeat - Keep a run's heartbeat_at fresh until stop closes, so other instances leave it alone
+func (s *Scheduler) heartbeat(runID uint, stop <-chan struct{}) {
+	ticker := time.NewTicker(jobRunHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if err := s.DB.Model(&models.ScheduledJobRun{}).Where("id = ?", runID).Update("heartbeat_at", now).Error; err != nil {
+				log.Printf("⚠️  Failed to refresh heartbeat of run %d: %v", runID, err)
+			}
+		}
+	}
+}
+
+// execute - Run the job's kind, turning a panic into a failed run
+func (s *Scheduler) execute(job models.ScheduledJob) (result *jobResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	kind, ok := scheduledJobKinds[job.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown kind: %s", job.Kind)
+	}
+	return kind.Run(s, job, filepath.Join(s.Dir, fmt.Sprintf("job_%d", job.ID)))
+}
+
+// decodeJobParams - Strict JSON decoding of a job's params ("" is an empty object)
+func decodeJobParams(raw string, v interface{}) error {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid params: %v", err)
+	}
+	return nil
+}
+
+// writeJobOutput - Write a run's output file through a temp file, so a failed run leaves nothing behind
+func writeJobOutput(dir, name string, write func(w io.Writer) (int64, error)) (*jobResult, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, "run-*.part")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	path := filepath.Join(dir, name)
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &jobResult{Output: path, OutputName: name, Rows: rows}, nil
+}
+
+// detachedQueryCtx - A fiber context holding only a query string, so jobs can reuse the HTTP filter
+// parsing. Call release when done.
+func detachedQueryCtx(query string) (ctx *fiber.Ctx, release func()) {
+	app := fiber.New()
+	request := &fasthttp.RequestCtx{}
+	request.Request.SetRequestURI("/?" + strings.TrimPrefix(query, "?"))
+	ctx = app.AcquireCtx(request)
+	return ctx, func() { app.ReleaseCtx(ctx) }
+}
@@ -0,0 +1,494 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"pipeline-backend/models"
+	"pipeline-backend/pagination"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// scheduledJobKinds - What a scheduled job can do, by kind
+var scheduledJobKinds = map[string]scheduledJobKind{
+	"report":    {Validate: validateReportJob, Run: runReportJob},
+	"export":    {Validate: validateExportJob, Run: runExportJob},
+	"recovery":  {Validate: validateRecoveryJob, Run: runRecoveryJob},
+	"retention": {Validate: validateRetentionJob, Run: runRetentionJob},
+}
+
+// reportJobParams - {"region": "R1"} or {"uker": "10272"}; the reports land in /api/reports under the run's batch
+type reportJobParams struct {
+	Template string `json:"template"` // default branch_monthly
+	Uker     string `json:"uker"`
+	Region   string `json:"region"`
+	Periode  string `json:"periode"` // yyyy-mm or yyyy-mm-dd, default the latest DI319 periode at run time
+}
+
+func (p *reportJobParams) parse(raw string) error {
+	if err := decodeJobParams(raw, p); err != nil {
+		return err
+	}
+	if p.Template == "" {
+		p.Template = "branch_monthly"
+	}
+	if _, ok := reportTemplates[p.Template]; !ok {
+		return fmt.Errorf("invalid template: %s", p.Template)
+	}
+	if (p.Uker == "") == (p.Region == "") {
+		return fmt.Errorf("either uker or region is required")
+	}
+	if p.Periode != "" {
+		if _, _, err := parseComparePeriode("periode", p.Periode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateReportJob(raw string) error {
+	var p reportJobParams
+	return p.parse(raw)
+}
+
+// runReportJob - Generate the batch and wait for it; fails when any report failed
+func runReportJob(s *Scheduler, job models.ScheduledJob, dir string) (*jobResult, error) {
+	var p reportJobParams
+	if err := p.parse(job.Params); err != nil {
+		return nil, err
+	}
+	periode, err := s.Reports.resolveReportPeriode(p.Periode)
+	if err != nil {
+		return nil, err
+	}
+	if periode == nil {
+		return nil, fmt.Errorf("no imported DI319 periode matches")
+	}
+	ukers, err := s.Reports.reportUkers(p.Uker, p.Region)
+	if err != nil {
+		return nil, err
+	}
+	if len(ukers) == 0 {
+		return nil, fmt.Errorf("no uker found")
+	}
+
+	batchID, reports, err := s.Reports.createReportBatch(p.Template, ukers, *periode, job.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	var failed int64
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, report := range reports {
+		wg.Add(1)
+		go func(report models.Report) {
+			defer wg.Done()
+			if err := s.Reports.runReport(report); err != nil {
+				mu.Lock()
+				failed++
+				mu.Unlock()
+			}
+		}(report)
+	}
+	wg.Wait()
+
+	if failed > 0 {
+		return nil, fmt.Errorf("batch %s: %d of %d reports failed", batchID, failed, len(reports))
+	}
+	return &jobResult{
+		Message: fmt.Sprintf("batch %s: %d reports for periode %s", batchID, len(reports), periode.Format("2006-01-02")),
+		Rows:    int64(len(reports)),
+	}, nil
+}
+
+// exportJobParams - A DI319 list (csv / xlsx) or stats workbook export, filtered like the HTTP endpoints
+type exportJobParams struct {
+	Dataset       string `json:"dataset"`        // di319 (default) or stats
+	Format        string `json:"format"`         // di319: csv (default) or xlsx; stats is always xlsx
+	Query         string `json:"query"`          // filters as on /api/di319/export or /api/stats/export, e.g. "branch=10272&rule=balance_drop_50pct"
+	LatestPeriode bool   `json:"latest_periode"` // only the latest DI319 periode at run time
+}
+
+func (p *exportJobParams) parse(raw string) error {
+	if err := decodeJobParams(raw, p); err != nil {
+		return err
+	}
+	if p.Dataset == "" {
+		p.Dataset = "di319"
+	}
+	switch p.Dataset {
+	case "di319":
+		if p.Format == "" {
+			p.Format = "csv"
+		}
+		if p.Format != "csv" && p.Format != "xlsx" {
+			return fmt.Errorf("invalid format: %s (expected csv or xlsx)", p.Format)
+		}
+	case "stats":
+		if p.Format != "" && p.Format != "xlsx" {
+			return fmt.Errorf("invalid format: %s (stats exports are xlsx)", p.Format)
+		}
+		p.Format = "xlsx"
+	default:
+		return fmt.Errorf("invalid dataset: %s (expected di319 or stats)", p.Dataset)
+	}
+
+	// Parse the filters now, so a bad query is rejected when the job is saved
+	ctx, release := detachedQueryCtx(p.Query)
+	defer release()
+	if p.Dataset == "stats" {
+		_, err := parseDI319StatsRequest(ctx, []string{"branch", "type"}, pagination.MaxPageSize)
+		return err
+	}
+	_, err := pagination.Parse(ctx, di319ListSpec)
+	return err
+}
+
+func validateExportJob(raw string) error {
+	var p exportJobParams
+	return p.parse(raw)
+}
+
+func runExportJob(s *Scheduler, job models.ScheduledJob, dir string) (*jobResult, error) {
+	var p exportJobParams
+	if err := p.parse(job.Params); err != nil {
+		return nil, err
+	}
+
+	var latest string
+	if p.LatestPeriode {
+		var periode sql.NullTime
+		if err := s.DB.Raw("SELECT MAX(periode) FROM di319").Row().Scan(&periode); err != nil {
+			return nil, err
+		}
+		if !periode.Valid {
+			return nil, fmt.Errorf("no DI319 data imported")
+		}
+		latest = periode.Time.Format("2006-01-02")
+	}
+
+	ctx, release := detachedQueryCtx(p.Query)
+	defer release()
+	di319 := &DI319ImportController{DB: s.DB}
+	name := fmt.Sprintf("%s_%s.%s", p.Dataset, time.Now().Format("20060102_150405"), p.Format)
+
+	if p.Dataset == "stats" {
+		r, err := parseDI319StatsRequest(ctx, []string{"branch", "type"}, pagination.MaxPageSize)
+		if err != nil {
+			return nil, err
+		}
+		if latest != "" {
+			r.Filter.Add("periode = ?", latest)
+		}
+		stats, failures := di319.computeDI319Stats(r)
+		if len(failures) > 0 {
+			return nil, fmt.Errorf("failed to compute stats: %s", strings.Join(failures, "; "))
+		}
+		result, err := writeJobOutput(dir, name, func(w io.Writer) (int64, error) {
+			workbook, rows, err := buildDI319StatsWorkbook(stats, r.Dimensions)
+			if err != nil {
+				return 0, err
+			}
+			defer workbook.Close()
+			return rows, workbook.Write(w)
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Message = fmt.Sprintf("%d groups exported", result.Rows)
+		return result, nil
+	}
+
+	params, err := pagination.Parse(ctx, di319ListSpec)
+	if err != nil {
+		return nil, err
+	}
+	addDI319RuleFilter(ctx, params.Filter)
+	if latest != "" {
+		params.Filter.Add("periode = ?", latest)
+	}
+	result, err := writeJobOutput(dir, name, func(w io.Writer) (int64, error) {
+		rows, err := params.Filter.Apply(s.DB.Model(&models.DI319{})).Order(params.Keyset.OrderSQL()).Rows()
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+		if p.Format == "xlsx" {
+			return di319.writeDI319XLSX(w, rows)
+		}
+		return di319.writeDI319CSV(w, rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Message = fmt.Sprintf("%d rows exported", result.Rows)
+	return result, nil
+}
+
+// recoveryJobParams - Recovery of the latest candidate periodes that have a later snapshot, as a CSV
+type recoveryJobParams struct {
+	By       string `json:"by"`       // uker (default), main_branch, region or rmft
+	Periodes int    `json:"periodes"` // how many periodes, newest first (default 1, max 24)
+}
+
+func (p *recoveryJobParams) parse(raw string) error {
+	if err := decodeJobParams(raw, p); err != nil {
+		return err
+	}
+	if p.By == "" {
+		p.By = "uker"
+	}
+	if _, ok := leaderboardBoards[p.By]; !ok {
+		return fmt.Errorf("invalid by: %s", p.By)
+	}
+	if p.Periodes == 0 {
+		p.Periodes = 1
+	}
+	if p.Periodes < 0 || p.Periodes > 24 {
+		return fmt.Errorf("periodes must be between 1 and 24")
+	}
+	return nil
+}
+
+func validateRecoveryJob(raw string) error {
+	var p recoveryJobParams
+	return p.parse(raw)
+}
+
+// runRecoveryJob - The leaderboard recovery figures (top 1000 groups per periode) of each periode,
+// measured against the next snapshot periode
+func runRecoveryJob(s *Scheduler, job models.ScheduledJob, dir string) (*jobResult, error) {
+	var p recoveryJobParams
+	if err := p.parse(job.Params); err != nil {
+		return nil, err
+	}
+
+	var periodes []time.Time
+	err := s.DB.Table("di319").
+		Where("qualifying_rule <> '' AND periode < (SELECT MAX(periode) FROM di319_snapshot)").
+		Distinct("periode").Order("periode DESC").Limit(p.Periodes).Pluck("periode", &periodes).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(periodes) == 0 {
+		return nil, fmt.Errorf("no candidate periode has a later snapshot yet")
+	}
+
+	leaderboards := &LeaderboardController{DB: s.DB}
+	name := fmt.Sprintf("recovery_%s_%s.csv", p.By, time.Now().Format("20060102_150405"))
+	result, err := writeJobOutput(dir, name, func(w io.Writer) (int64, error) {
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"periode", "recovery_periode", p.By, "label", "candidates", "total_drop",
+			"followed_up", "recovered", "recovery_rate"})
+
+		var written int64
+		for _, periode := range periodes {
+			recovery, err := leaderboards.nextSnapshotPeriode(periode)
+			if err != nil {
+				return written, err
+			}
+			entries, err := leaderboards.rankLeaderboard(p.By, &leaderboardQuery{
+				Periode:         periode,
+				RecoveryPeriode: recovery,
+				Filter:          &pagination.Filter{},
+				Sort:            "total_drop",
+				Desc:            true,
+				Limit:           pagination.MaxPageSize,
+			})
+			if err != nil {
+				return written, err
+			}
+			for _, e := range entries {
+				recovered, rate := "", ""
+				if e.Recovered != nil {
+					recovered = e.Recovered.String()
+				}
+				if e.RecoveryRate != nil {
+					rate = strconv.FormatFloat(*e.RecoveryRate, 'f', 2, 64)
+				}
+				writer.Write([]string{periode.Format("2006-01-02"), recovery.Format("2006-01-02"), e.Name, e.Label,
+					strconv.FormatInt(e.Candidates, 10), e.TotalDrop.String(), strconv.FormatInt(e.FollowedUp, 10),
+					recovered, rate})
+				written++
+			}
+		}
+		writer.Flush()
+		return written, writer.Error()
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Message = fmt.Sprintf("%d rows over %d periodes", result.Rows, len(periodes))
+	return result, nil
+}
+
+// retentionJobParams - How much history to keep; 0 keeps everything of that kind
+type retentionJobParams struct {
+	DI319Months    int `json:"di319_months"`    // periodes in di319 (and di319_summary), counting the current month
+	SnapshotMonths int `json:"snapshot_months"` // periodes in di319_snapshot
+	AuditLogDays   int `json:"audit_log_days"`
+	ReportDays     int `json:"report_days"`  // generated reports and their files
+	JobRunDays     int `json:"job_run_days"` // scheduled job run history and output files
+}
+
+func (p *retentionJobParams) parse(raw string) error {
+	if err := decodeJobParams(raw, p); err != nil {
+		return err
+	}
+	for name, v := range map[string]int{"di319_months": p.DI319Months, "snapshot_months": p.SnapshotMonths,
+		"audit_log_days": p.AuditLogDays, "report_days": p.ReportDays, "job_run_days": p.JobRunDays} {
+		if v < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if *p == (retentionJobParams{}) {
+		return fmt.Errorf("nothing to clean up: set at least one of di319_months, snapshot_months, audit_log_days, report_days, job_run_days")
+	}
+	return nil
+}
+
+func validateRetentionJob(raw string) error {
+	var p retentionJobParams
+	return p.parse(raw)
+}
+
+// retentionChunk - Rows deleted per statement, so a cleanup never holds long locks
+const retentionChunk = 10000
+
+func runRetentionJob(s *Scheduler, job models.ScheduledJob, dir string) (*jobResult, error) {
+	var p retentionJobParams
+	if err := p.parse(job.Params); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var parts []string
+	var total int64
+	note := func(what string, n int64) {
+		parts = append(parts, fmt.Sprintf("%s=%d", what, n))
+		total += n
+	}
+
+	if p.DI319Months > 0 {
+		cutoff := monthStart.AddDate(0, 1-p.DI319Months, 0).Format("2006-01-02")
+		n, err := deleteInChunks(s.DB, "di319", "periode < ?", cutoff)
+		if err != nil {
+			return nil, fmt.Errorf("di319: %w", err)
+		}
+		note("di319", n)
+		if err := s.DB.Exec("DELETE FROM di319_summary WHERE periode < ?", cutoff).Error; err != nil {
+			return nil, fmt.Errorf("di319_summary: %w", err)
+		}
+	}
+
+	if p.SnapshotMonths > 0 {
+		cutoff := monthStart.AddDate(0, 1-p.SnapshotMonths, 0)
+		if err := s.dropSnapshotPartitionsBefore(cutoff); err != nil {
+			return nil, fmt.Errorf("di319_snapshot partitions: %w", err)
+		}
+		// A partition covering several months (the first one, or one left by skipped months) can span the cutoff
+		n, err := deleteInChunks(s.DB, "di319_snapshot", "periode < ?", cutoff.Format("2006-01-02"))
+		if err != nil {
+			return nil, fmt.Errorf("di319_snapshot: %w", err)
+		}
+		note("di319_snapshot", n)
+	}
+
+	if p.AuditLogDays > 0 {
+		n, err := deleteInChunks(s.DB, "audit_logs", "created_at < ?", now.AddDate(0, 0, -p.AuditLogDays))
+		if err != nil {
+			return nil, fmt.Errorf("audit_logs: %w", err)
+		}
+		note("audit_logs", n)
+	}
+
+	if p.ReportDays > 0 {
+		var reports []models.Report
+		err := s.DB.Select("id", "path").Where("created_at < ? AND status IN ?", now.AddDate(0, 0, -p.ReportDays),
+			[]string{models.ReportDone, models.ReportFailed}).Find(&reports).Error
+		if err != nil {
+			return nil, fmt.Errorf("reports: %w", err)
+		}
+		for _, report := range reports {
+			if report.Path != "" {
+				os.Remove(report.Path)
+			}
+			if err := s.DB.Delete(&models.Report{}, report.ID).Error; err != nil {
+				return nil, fmt.Errorf("reports: %w", err)
+			}
+		}
+		note("reports", int64(len(reports)))
+	}
+
+	if p.JobRunDays > 0 {
+		var runs []models.ScheduledJobRun
+		err := s.DB.Select("id", "output").Where("started_at < ? AND status <> ?", now.AddDate(0, 0, -p.JobRunDays),
+			models.JobRunRunning).Find(&runs).Error
+		if err != nil {
+			return nil, fmt.Errorf("scheduled_job_runs: %w", err)
+		}
+		for _, run := range runs {
+			if run.Output != "" {
+				os.Remove(run.Output)
+			}
+			if err := s.DB.Delete(&models.ScheduledJobRun{}, run.ID).Error; err != nil {
+				return nil, fmt.Errorf("scheduled_job_runs: %w", err)
+			}
+		}
+		note("scheduled_job_runs", int64(len(runs)))
+	}
+
+	return &jobResult{Message: "deleted " + strings.Join(parts, ", "), Rows: total}, nil
+}
+
+// deleteInChunks - DELETE ... LIMIT retentionChunk until nothing is left; returns the rows deleted
+func deleteInChunks(db *gorm.DB, table, where string, args ...interface{}) (int64, error) {
+	var total int64
+	for {
+		result := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s LIMIT %d", table, where, retentionChunk), args...)
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < retentionChunk {
+			return total, nil
+		}
+	}
+}
+
+// dropSnapshotPartitionsBefore - Drop the monthly di319_snapshot partitions that hold only periodes before cutoff
+func (s *Scheduler) dropSnapshotPartitionsBefore(cutoff time.Time) error {
+	var partitions []struct {
+		Name  string
+		Bound string
+	}
+	err := s.DB.Raw(`SELECT PARTITION_NAME AS name, PARTITION_DESCRIPTION AS bound
+		FROM information_schema.PARTITIONS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'di319_snapshot' AND PARTITION_NAME IS NOT NULL`).
+		Scan(&partitions).Error
+	if err != nil {
+		return err
+	}
+
+	for _, p := range partitions {
+		if p.Name == "pmax" {
+			continue
+		}
+		bound, err := time.Parse("'2006-01-02'", p.Bound)
+		if err != nil || bound.After(cutoff) {
+			continue
+		}
+		if err := s.DB.Exec(fmt.Sprintf("ALTER TABLE di319_snapshot DROP PARTITION %s", p.Name)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
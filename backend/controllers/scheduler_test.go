@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextJobRun(t *testing.T) {
+	from := time.Date(2025, 3, 14, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr    string
+		want    time.Time
+		wantErr bool
+	}{
+		{"0 2 * * *", time.Date(2025, 3, 15, 2, 0, 0, 0, time.UTC), false},
+		{"*/15 * * * *", time.Date(2025, 3, 14, 10, 45, 0, 0, time.UTC), false},
+		{"@daily", time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC), false},
+		{"@every 1h", time.Date(2025, 3, 14, 11, 30, 0, 0, time.UTC), false},
+		{"0 9 1 * *", time.Date(2025, 4, 1, 9, 0, 0, 0, time.UTC), false},
+		{"CRON_TZ=Asia/Jakarta 0 7 * * *", time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC), false},
+		{"0 0 0 * * *", time.Time{}, true}, // seconds field is not accepted
+		{"daily", time.Time{}, true},
+		{"", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := nextJobRun(tt.expr, from)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("nextJobRun(%q) error = %v, want error %v", tt.expr, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !got.Equal(tt.want) {
+			t.Errorf("nextJobRun(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestRetentionJobParams(t *testing.T) {
+	tests := []struct {
+		raw     string
+		wantErr bool
+	}{
+		{`{"di319_months": 24}`, false},
+		{`{"audit_log_days": 365, "job_run_days": 30}`, false},
+		{``, true},   // nothing to clean up
+		{`{}`, true}, // nothing to clean up
+		{`{"report_days": -1}`, true},
+		{`{"di319_months": 12, "unknown": 1}`, true},
+		{`{"di319_months": "12"}`, true},
+	}
+	for _, tt := range tests {
+		if err := validateRetentionJob(tt.raw); (err != nil) != tt.wantErr {
+			t.Errorf("validateRetentionJob(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"pipeline-backend/pagination"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Relevance for number lookups, ranked above FULLTEXT scores (which rarely exceed ~30)
+const (
+	searchExactScore  = 100.0
+	searchPrefixScore = 50.0
+)
+
+type SearchController struct {
+	DB *gorm.DB
+}
+
+func NewSearchController(db *gorm.DB) *SearchController {
+	return &SearchController{DB: db}
+}
+
+// searchCustomer - A DI319 customer (CIF) matching the query
+type searchCustomer struct {
+	CIF           string    `json:"cif"`
+	Nama          string    `json:"nama"`
+	Accounts      int64     `json:"accounts"`
+	Branch        string    `json:"branch"`
+	LatestPeriode time.Time `json:"latest_periode"`
+	Score         float64   `json:"score"`
+}
+
+// searchRMFT - An RMFT officer matching the query
+type searchRMFT struct {
+	ID          uint    `json:"id"`
+	PN          string  `json:"pn"`
+	NamaLengkap string  `json:"nama_lengkap"`
+	Kanca       string  `json:"kanca"`
+	Uker        string  `json:"uker"`
+	Score       float64 `json:"score"`
+}
+
+// searchUker - A uker matching the query
+type searchUker struct {
+	ID         int     `json:"id"`
+	KodeUker   string  `json:"kode_uker"`
+	NamaUker   string  `json:"nama_uker"`
+	MainBranch string  `json:"main_branch"`
+	Region     string  `json:"region"`
+	Score      float64 `json:"score"`
+}
+
+// Search - Search customers, RMFT officers and ukers (GET /api/search?q=&limit=)
+// Names go through the FULLTEXT indexes (every word required, prefix matching);
+// numeric queries also match cif / norek / pn / kode_uker by exact value or prefix.
+// Any failed query (e.g. a missing FULLTEXT index) answers 500 with the failures, never empty groups.
+func (c *SearchController) Search(ctx *fiber.Ctx) error {
+	q := strings.TrimSpace(ctx.Query("q"))
+	if len([]rune(q)) < 2 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "q must be at least 2 characters",
+		})
+	}
+
+	limit, err := strconv.Atoi(ctx.Query("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	scope, err := di319ScopeFilter(c.DB, ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load data scope",
+		})
+	}
+
+	booleanQuery := fulltextBooleanQuery(q)
+	prefix := escapeLike(q) + "%"
+
+	var customers []searchCustomer
+	var rmfts []searchRMFT
+	var ukers []searchUker
+
+	// Run the three searches in parallel; a failed query fails the request rather than look like no match
+	var mu sync.Mutex
+	var failures []string
+	fail := func(what string, err error) {
+		mu.Lock()
+		failures = append(failures, fmt.Sprintf("%s: %v", what, err))
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		var err error
+		if customers, err = c.searchCustomers(q, prefix, booleanQuery, limit, scope); err != nil {
+			fail("customers", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		if rmfts, err = c.searchRMFTs(q, prefix, booleanQuery, limit); err != nil {
+			fail("rmfts", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		if ukers, err = c.searchUkers(q, prefix, booleanQuery, limit); err != nil {
+			fail("ukers", err)
+		}
+	}()
+	wg.Wait()
+
+	if len(failures) > 0 {
+		log.Printf("⚠️  Search %q failed: %s", q, strings.Join(failures, "; "))
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":    "Search failed",
+			"failures": failures,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"query": q,
+		"results": fiber.Map{
+			"customers": customers,
+			"rmfts":     rmfts,
+			"ukers":     ukers,
+		},
+		"total": len(customers) + len(rmfts) + len(ukers),
+	})
+}
+
+// searchCustomers - DI319 customers within the user's data scope grouped by CIF, by name (FULLTEXT) or cif / norek
+func (c *SearchController) searchCustomers(q, prefix, booleanQuery string, limit int, scope *pagination.Filter) ([]searchCustomer, error) {
+	const columns = `cif, MAX(nama) AS nama, COUNT(DISTINCT norek) AS accounts,
+		MAX(branch) AS branch, MAX(periode) AS latest_periode`
+
+	var results []searchCustomer
+	if booleanQuery != "" {
+		args := append(append([]interface{}{booleanQuery, booleanQuery}, scope.Args...), limit)
+		err := c.DB.Raw("SELECT "+columns+", MAX(MATCH(nama) AGAINST (? IN BOOLEAN MODE)) AS score"+
+			" FROM di319 WHERE MATCH(nama) AGAINST (? IN BOOLEAN MODE) AND "+scope.SQL()+
+			" GROUP BY cif ORDER BY score DESC LIMIT ?", args...).
+			Scan(&results).Error
+		if err != nil {
+			return nil, fmt.Errorf("name search: %w", err)
+		}
+	}
+
+	if isDigits(q) {
+		var byNumber []searchCustomer
+		args := append(append([]interface{}{q, q, searchExactScore, searchPrefixScore, prefix, prefix}, scope.Args...), limit)
+		err := c.DB.Raw("SELECT "+columns+", MAX(CASE WHEN cif = ? OR norek = ? THEN ? ELSE ? END) AS score"+
+			" FROM di319 WHERE (cif LIKE ? OR norek LIKE ?) AND "+scope.SQL()+
+			" GROUP BY cif ORDER BY score DESC, cif LIMIT ?", args...).
+			Scan(&byNumber).Error
+		if err != nil {
+			return nil, fmt.Errorf("number search: %w", err)
+		}
+		results = mergeSearchResults(results, byNumber, func(r searchCustomer) string { return r.CIF },
+			func(r searchCustomer) float64 { return r.Score })
+	}
+
+	return trimSearchResults(results, limit), nil
+}
+
+// searchRMFTs - RMFT officers by name (FULLTEXT) or PN
+func (c *SearchController) searchRMFTs(q, prefix, booleanQuery string, limit int) ([]searchRMFT, error) {
+	const columns = "id, pn, nama_lengkap, kanca, uker"
+
+	var results []searchRMFT
+	if booleanQuery != "" {
+		err := c.DB.Raw("SELECT "+columns+", MATCH(nama_lengkap) AGAINST (? IN BOOLEAN MODE) AS score"+
+			" FROM rfmts WHERE deleted_at IS NULL AND MATCH(nama_lengkap) AGAINST (? IN BOOLEAN MODE)"+
+			" ORDER BY score DESC LIMIT ?", booleanQuery, booleanQuery, limit).
+			Scan(&results).Error
+		if err != nil {
+			return nil, fmt.Errorf("name search: %w", err)
+		}
+	}
+
+	var byPN []searchRMFT
+	err := c.DB.Raw("SELECT "+columns+", CASE WHEN pn = ? THEN ? ELSE ? END AS score"+
+		" FROM rfmts WHERE deleted_at IS NULL AND pn LIKE ?"+
+		" ORDER BY score DESC, pn LIMIT ?", q, searchExactScore, searchPrefixScore, prefix, limit).
+		Scan(&byPN).Error
+	if err != nil {
+		return nil, fmt.Errorf("pn search: %w", err)
+	}
+	results = mergeSearchResults(results, byPN, func(r searchRMFT) string { return strconv.FormatUint(uint64(r.ID), 10) },
+		func(r searchRMFT) float64 { return r.Score })
+
+	return trimSearchResults(results, limit), nil
+}
+
+// searchUkers - Ukers by name (FULLTEXT) or kode_uker
+func (c *SearchController) searchUkers(q, prefix, booleanQuery string, limit int) ([]searchUker, error) {
+	const columns = "id, kode_uker, nama_uker, main_branch, region"
+
+	var results []searchUker
+	if booleanQuery != "" {
+		err := c.DB.Raw("SELECT "+columns+", MATCH(nama_uker) AGAINST (? IN BOOLEAN MODE) AS score"+
+			" FROM uker WHERE MATCH(nama_uker) AGAINST (? IN BOOLEAN MODE)"+
+			" ORDER BY score DESC LIMIT ?", booleanQuery, booleanQuery, limit).
+			Scan(&results).Error
+		if err != nil {
+			return nil, fmt.Errorf("name search: %w", err)
+		}
+	}
+
+	var byCode []searchUker
+	err := c.DB.Raw("SELECT "+columns+", CASE WHEN kode_uker = ? THEN ? ELSE ? END AS score"+
+		" FROM uker WHERE kode_uker LIKE ?"+
+		" ORDER BY score DESC, kode_uker LIMIT ?", q, searchExactScore, searchPrefixScore, prefix, limit).
+		Scan(&byCode).Error
+	if err != nil {
+		return nil, fmt.Errorf("code search: %w", err)
+	}
+	results = mergeSearchResults(results, byCode, func(r searchUker) string { return strconv.Itoa(r.ID) },
+		func(r searchUker) float64 { return r.Score })
+
+	return trimSearchResults(results, limit), nil
+}
+
+// fulltextBooleanQuery - "budi sant" -> "+budi* +sant*". Operator characters are dropped so user
+// input cannot change the query's meaning; returns "" when no word is left.
+func fulltextBooleanQuery(q string) string {
+	words := strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, "+"+word+"*")
+	}
+	return strings.Join(terms, " ")
+}
+
+// escapeLike - Escape LIKE wildcards in user input
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// isDigits - Whether s only holds digits (CIF / norek lookups)
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// mergeSearchResults - Union two result lists by key, keeping the higher score, best first
+func mergeSearchResults[T any](a, b []T, key func(T) string, score func(T) float64) []T {
+	index := make(map[string]int, len(a)+len(b))
+	merged := make([]T, 0, len(a)+len(b))
+	for _, r := range append(a, b...) {
+		if i, ok := index[key(r)]; ok {
+			if score(r) > score(merged[i]) {
+				merged[i] = r
+			}
+			continue
+		}
+		index[key(r)] = len(merged)
+		merged = append(merged, r)
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return score(merged[i]) > score(merged[j]) })
+	return merged
+}
+
+// trimSearchResults - Cap a group at limit, never returning null
+func trimSearchResults[T any](results []T, limit int) []T {
+	if results == nil {
+		return []T{}
+	}
+	if len(results) > limit {
+		return results[:limit]
+	}
+	return results
+}
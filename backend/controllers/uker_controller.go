@@ -2,7 +2,7 @@ package controllers
 
 import (
 	"pipeline-backend/models"
-	"strconv"
+	"pipeline-backend/pagination"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -16,55 +16,54 @@ func NewUkerController(db *gorm.DB) *UkerController {
 	return &UkerController{DB: db}
 }
 
+// ukerListSpec - Paging, sorting and filtering of GET /api/ukers (legacy keys: total/page/limit)
+var ukerListSpec = pagination.Spec{
+	Table: "uker",
+	Sorts: map[string]pagination.Sort{
+		"id":        {},
+		"kode_uker": {Column: "kode_uker"},
+		"nama_uker": {Column: "nama_uker"},
+		"region":    {Column: "region"},
+	},
+	DefaultSort: "id",
+	Filters: []pagination.FilterField{
+		{Param: "kode_uker", Column: "kode_uker", Kind: pagination.FilterEqual},
+		{Param: "region", Column: "region", Kind: pagination.FilterEqual},
+		{Param: "active", Column: "ACTIVE", Kind: pagination.FilterEqual},
+	},
+	SearchColumns: []string{"kode_uker", "nama_uker", "main_branch", "region"},
+	Legacy: func(m pagination.Meta) fiber.Map {
+		total := int64(0)
+		if m.Total != nil {
+			total = *m.Total
+		}
+		return fiber.Map{
+			"total":          total,
+			"page":           m.Page,
+			"limit":          m.PageSize,
+			"total_estimate": m.TotalIsEstimate,
+			"next_cursor":    m.NextCursor,
+			"has_more":       m.HasMore,
+		}
+	},
+}
+
 // GetAll - Get all Ukers with pagination and filters
 func (c *UkerController) GetAll(ctx *fiber.Ctx) error {
-	page, _ := strconv.Atoi(ctx.Query("page", "1"))
-	limit, _ := strconv.Atoi(ctx.Query("limit", "10"))
-	search := ctx.Query("search", "")
-	kodeUker := ctx.Query("kode_uker", "")
-	region := ctx.Query("region", "")
-	active := ctx.Query("active", "")
-
-	offset := (page - 1) * limit
-
-	var ukers []models.Uker
-	var total int64
-
-	query := c.DB.Model(&models.Uker{})
-
-	// Filter by kode_uker if provided
-	if kodeUker != "" {
-		query = query.Where("kode_uker = ?", kodeUker)
+	params, err := pagination.Parse(ctx, ukerListSpec)
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	// Filter by region if provided
-	if region != "" {
-		query = query.Where("region = ?", region)
+	result, err := pagination.List[models.Uker](c.DB, c.DB.Model(&models.Uker{}), params)
+	if err == pagination.ErrInvalidCursor {
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	// Filter by active status if provided
-	if active != "" {
-		query = query.Where("ACTIVE = ?", active)
-	}
-
-	// Search filter
-	if search != "" {
-		query = query.Where("kode_uker LIKE ? OR nama_uker LIKE ? OR main_branch LIKE ? OR region LIKE ?",
-			"%"+search+"%", "%"+search+"%", "%"+search+"%", "%"+search+"%")
-	}
-
-	query.Count(&total)
-
-	if err := query.Offset(offset).Limit(limit).Order("id ASC").Find(&ukers).Error; err != nil {
+	if err != nil {
 		return ctx.Status(500).JSON(fiber.Map{"error": "Failed to fetch Ukers"})
 	}
 
-	return ctx.JSON(fiber.Map{
-		"data":  ukers,
-		"total": total,
-		"page":  page,
-		"limit": limit,
-	})
+	return pagination.Respond(ctx, params, result)
 }
 
 // GetByID - Get single Uker by ID
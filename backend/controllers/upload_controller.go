@@ -0,0 +1,390 @@
+package controllers
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"pipeline-backend/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Resumable upload protocol (tus 1.0 core + checksum extension):
+//
+//	POST   /api/uploads      Upload-Length, Upload-Metadata (filename, sha256) -> 201 + Location
+//	HEAD   /api/uploads/:id  -> Upload-Offset / Upload-Length
+//	PATCH  /api/uploads/:id  Upload-Offset, Upload-Checksum (optional), body = next chunk
+//	GET    /api/uploads/:id  -> upload record as JSON
+//	DELETE /api/uploads/:id  -> discard staged file
+//
+// A completed upload is handed to an importer with the "upload_id" form field.
+const (
+	tusVersion                   = "1.0.0"
+	statusChecksumMismatch       = 460
+	defaultUploadMaxSize   int64 = 10 * 1024 * 1024 * 1024 // 10GB
+)
+
+// uploadLocks - Serialises PATCH requests per upload ID
+var uploadLocks sync.Map
+
+type UploadController struct {
+	DB  *gorm.DB
+	Dir string
+}
+
+func NewUploadController(db *gorm.DB) *UploadController {
+	dir := os.Getenv("UPLOAD_DIR")
+	if dir == "" {
+		dir = "uploads"
+	}
+	return &UploadController{DB: db, Dir: dir}
+}
+
+// Create - Start a new resumable upload
+func (c *UploadController) Create(ctx *fiber.Ctx) error {
+	c.setTusHeaders(ctx)
+
+	size, err := strconv.ParseInt(ctx.Get("Upload-Length"), 10, 64)
+	if err != nil || size <= 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Upload-Length header is required"})
+	}
+
+	maxSize := int64(envInt("UPLOAD_MAX_SIZE", 0))
+	if maxSize <= 0 {
+		maxSize = defaultUploadMaxSize
+	}
+	if size > maxSize {
+		return ctx.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": fmt.Sprintf("Upload exceeds maximum size of %d bytes", maxSize),
+		})
+	}
+
+	metadata := parseUploadMetadata(ctx.Get("Upload-Metadata"))
+	filename := filepath.Base(metadata["filename"])
+	if filename == "" || filename == "." {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "filename is required in Upload-Metadata"})
+	}
+
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create upload directory"})
+	}
+
+	id := uuid.NewString()
+	path := filepath.Join(c.Dir, id+".part")
+	f, err := os.Create(path)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create upload file"})
+	}
+	f.Close()
+
+	upload := models.Upload{
+		ID:             id,
+		Filename:       filename,
+		Size:           size,
+		Status:         "uploading",
+		ExpectedSHA256: strings.ToLower(metadata["sha256"]),
+		Path:           path,
+	}
+	if userID, ok := ctx.Locals("user_id").(uint); ok {
+		upload.UserID = userID
+	}
+
+	if err := c.DB.Create(&upload).Error; err != nil {
+		os.Remove(path)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create upload"})
+	}
+
+	ctx.Set("Location", "/api/uploads/"+id)
+	ctx.Set("Upload-Offset", "0")
+	return ctx.Status(fiber.StatusCreated).JSON(upload)
+}
+
+// Head - Report the current offset so a client can resume
+func (c *UploadController) Head(ctx *fiber.Ctx) error {
+	c.setTusHeaders(ctx)
+
+	var upload models.Upload
+	if err := ownUploads(ctx, c.DB).First(&upload, "id = ?", ctx.Params("id")).Error; err != nil {
+		return ctx.SendStatus(fiber.StatusNotFound)
+	}
+
+	ctx.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	ctx.Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	ctx.Set("Cache-Control", "no-store")
+	return ctx.SendStatus(fiber.StatusOK)
+}
+
+// Get - Get upload record
+func (c *UploadController) Get(ctx *fiber.Ctx) error {
+	var upload models.Upload
+	if err := ownUploads(ctx, c.DB).First(&upload, "id = ?", ctx.Params("id")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Upload not found"})
+	}
+	return ctx.JSON(upload)
+}
+
+// Patch - Append the next chunk at Upload-Offset
+func (c *UploadController) Patch(ctx *fiber.Ctx) error {
+	c.setTusHeaders(ctx)
+	id := ctx.Params("id")
+
+	// Check ownership before taking a lock, so unknown IDs never add lock entries
+	var upload models.Upload
+	if err := ownUploads(ctx, c.DB).Select("id").First(&upload, "id = ?", id).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Upload not found"})
+	}
+
+	lock, _ := uploadLocks.LoadOrStore(id, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	if err := c.DB.First(&upload, "id = ?", id).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Upload not found"})
+	}
+	if upload.Status != "uploading" {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Upload is already " + upload.Status})
+	}
+
+	offset, err := strconv.ParseInt(ctx.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Upload-Offset header is required"})
+	}
+	if offset != upload.Offset {
+		ctx.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":  "Upload-Offset does not match current offset",
+			"offset": upload.Offset,
+		})
+	}
+
+	chunk := ctx.Body()
+	if offset+int64(len(chunk)) > upload.Size {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Chunk exceeds Upload-Length"})
+	}
+
+	if checksum := ctx.Get("Upload-Checksum"); checksum != "" {
+		ok, err := verifyChunkChecksum(checksum, chunk)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !ok {
+			return ctx.Status(statusChecksumMismatch).JSON(fiber.Map{"error": "Checksum mismatch"})
+		}
+	}
+
+	f, err := os.OpenFile(upload.Path, os.O_WRONLY, 0o644)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to open upload file"})
+	}
+	_, err = f.WriteAt(chunk, offset)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to write chunk"})
+	}
+
+	upload.Offset = offset + int64(len(chunk))
+	if upload.Offset == upload.Size {
+		if err := c.finishUpload(&upload); err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	if err := c.DB.Save(&upload).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update upload"})
+	}
+
+	ctx.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if upload.Status == "corrupt" {
+		return ctx.Status(statusChecksumMismatch).JSON(fiber.Map{"error": "File checksum mismatch", "data": upload})
+	}
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// Delete - Discard an upload and its staged file
+func (c *UploadController) Delete(ctx *fiber.Ctx) error {
+	c.setTusHeaders(ctx)
+
+	var upload models.Upload
+	if err := ownUploads(ctx, c.DB).First(&upload, "id = ?", ctx.Params("id")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Upload not found"})
+	}
+
+	os.Remove(upload.Path)
+	if err := c.DB.Delete(&upload).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete upload"})
+	}
+	uploadLocks.Delete(upload.ID)
+
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// ownUploads - Uploads the caller may see: their own, or all of them for admins
+func ownUploads(ctx *fiber.Ctx, db *gorm.DB) *gorm.DB {
+	if ctx.Locals("role") == "admin" {
+		return db
+	}
+	userID, _ := ctx.Locals("user_id").(uint)
+	return db.Where("user_id = ?", userID)
+}
+
+// StartUploadSweeper - Every hour, remove uploads untouched for UPLOAD_TTL_HOURS (default 48; 0 disables):
+// abandoned partial uploads as well as completed ones that were imported or never used
+func StartUploadSweeper(db *gorm.DB) {
+	ttl := time.Duration(envInt("UPLOAD_TTL_HOURS", 48)) * time.Hour
+	if ttl <= 0 {
+		return
+	}
+	go func() {
+		for {
+			if n, err := sweepStaleUploads(db, time.Now().Add(-ttl)); err != nil {
+				log.Println("⚠️  Failed to sweep stale uploads:", err)
+			} else if n > 0 {
+				log.Printf("🧹 Removed %d stale uploads", n)
+			}
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+// sweepStaleUploads - Delete uploads last updated before cutoff, with their staged files and lock entries
+func sweepStaleUploads(db *gorm.DB, cutoff time.Time) (int, error) {
+	var uploads []models.Upload
+	if err := db.Select("id", "path").Where("updated_at < ?", cutoff).Find(&uploads).Error; err != nil {
+		return 0, err
+	}
+	for _, upload := range uploads {
+		if err := os.Remove(upload.Path); err != nil && !os.IsNotExist(err) {
+			log.Printf("⚠️  Failed to remove upload file %s: %v", upload.Path, err)
+			continue
+		}
+		if err := db.Delete(&models.Upload{}, "id = ?", upload.ID).Error; err != nil {
+			return 0, err
+		}
+		uploadLocks.Delete(upload.ID)
+	}
+	return len(uploads), nil
+}
+
+// finishUpload - Hash the assembled file and mark it completed (or corrupt)
+func (c *UploadController) finishUpload(upload *models.Upload) error {
+	f, err := os.Open(upload.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open upload file")
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to hash upload file")
+	}
+	upload.SHA256 = hex.EncodeToString(h.Sum(nil))
+
+	if upload.ExpectedSHA256 != "" && upload.ExpectedSHA256 != upload.SHA256 {
+		upload.Status = "corrupt"
+		return nil
+	}
+	upload.Status = "completed"
+	return nil
+}
+
+// setTusHeaders - Headers every tus response carries
+func (c *UploadController) setTusHeaders(ctx *fiber.Ctx) {
+	ctx.Set("Tus-Resumable", tusVersion)
+	ctx.Set("Tus-Extension", "creation,checksum,termination")
+	ctx.Set("Tus-Checksum-Algorithm", "sha256,sha1,md5")
+}
+
+// parseUploadMetadata - Decode "key base64value,key base64value"
+func parseUploadMetadata(header string) map[string]string {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), " ", 2)
+		if parts[0] == "" {
+			continue
+		}
+		if len(parts) == 1 {
+			metadata[parts[0]] = ""
+			continue
+		}
+		if value, err := base64.StdEncoding.DecodeString(parts[1]); err == nil {
+			metadata[parts[0]] = string(value)
+		}
+	}
+	return metadata
+}
+
+// verifyChunkChecksum - Check "algorithm base64digest" against the chunk
+func verifyChunkChecksum(header string, chunk []byte) (bool, error) {
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if len(parts) != 2 {
+		return false, fmt.Errorf("invalid Upload-Checksum header")
+	}
+
+	var h hash.Hash
+	switch strings.ToLower(parts[0]) {
+	case "sha256":
+		h = sha256.New()
+	case "sha1":
+		h = sha1.New()
+	case "md5":
+		h = md5.New()
+	default:
+		return false, fmt.Errorf("unsupported checksum algorithm: %s", parts[0])
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false, fmt.Errorf("invalid Upload-Checksum digest")
+	}
+
+	h.Write(chunk)
+	return string(h.Sum(nil)) == string(expected), nil
+}
+
+// importSource - A re-openable file handed to an importer: a multipart upload or a completed resumable upload
+type importSource struct {
+	Filename string
+	Open     func() (multipart.File, error)
+}
+
+// resolveImportSource - Use "upload_id" when given, otherwise the multipart "file" field
+func resolveImportSource(ctx *fiber.Ctx, db *gorm.DB) (*importSource, error) {
+	if uploadID := ctx.FormValue("upload_id"); uploadID != "" {
+		var upload models.Upload
+		if err := ownUploads(ctx, db).First(&upload, "id = ?", uploadID).Error; err != nil {
+			return nil, fmt.Errorf("Upload not found")
+		}
+		if upload.Status != "completed" {
+			return nil, fmt.Errorf("Upload is not completed (status: %s)", upload.Status)
+		}
+		return &importSource{
+			Filename: upload.Filename,
+			Open: func() (multipart.File, error) {
+				return os.Open(upload.Path)
+			},
+		}, nil
+	}
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("No file uploaded")
+	}
+	return &importSource{Filename: file.Filename, Open: file.Open}, nil
+}
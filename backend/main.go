@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"pipeline-backend/config"
+	"pipeline-backend/controllers"
 	"pipeline-backend/models"
 	"pipeline-backend/routes"
 
@@ -66,22 +67,92 @@ func main() {
 	log.Println("📦 Registering di319 table (existing data preserved)...")
 	if db.Migrator().HasTable(&models.DI319{}) {
 		log.Println("✅ DI319 table found with existing data")
+		if err := migrateDI319Amounts(db); err != nil {
+			log.Fatal("Failed to migrate DI319 balance columns:", err)
+		}
+		if err := migrateDI319DropMetrics(db); err != nil {
+			log.Fatal("Failed to migrate DI319 drop metrics:", err)
+		}
 	} else {
 		log.Println("⚠️  Warning: DI319 table not found in database")
 	}
 
+	// Pre-aggregated DI319 summary behind the stats / analytics endpoints
+	if err = migrateDI319Summary(db); err != nil {
+		log.Fatal("Failed to migrate DI319Summary:", err)
+	}
+
+	// Full DI319 snapshots (optional import mode)
+	if err = migrateDI319Snapshot(db); err != nil {
+		log.Fatal("Failed to migrate DI319Snapshot:", err)
+	}
+
 	// Migrate product_type table
 	log.Println("📦 Creating product_type table...")
 	if err = db.AutoMigrate(&models.ProductType{}); err != nil {
 		log.Fatal("Failed to migrate ProductType:", err)
 	}
 
+	// Migrate import_profiles table and seed the built-in DI319 layout
+	log.Println("📦 Creating import_profiles table...")
+	if err = db.AutoMigrate(&models.ImportProfile{}); err != nil {
+		log.Fatal("Failed to migrate ImportProfile:", err)
+	}
+	var profileCount int64
+	db.Model(&models.ImportProfile{}).Where("name = ?", controllers.DefaultDI319ProfileName).Count(&profileCount)
+	if profileCount == 0 {
+		defaultProfile := controllers.DefaultDI319Profile()
+		if err := db.Create(&defaultProfile).Error; err != nil {
+			log.Println("⚠️  Failed to create default import profile:", err)
+		} else {
+			log.Println("✅ Default DI319 import profile created")
+		}
+	}
+
+	// Migrate uploads table (resumable chunked uploads)
+	log.Println("📦 Creating uploads table...")
+	if err = db.AutoMigrate(&models.Upload{}); err != nil {
+		log.Fatal("Failed to migrate Upload:", err)
+	}
+
 	// Then migrate RFMTs (child table with FK to uker)
 	log.Println("📦 Creating rfmts table with FK constraints (uker)...")
 	if err = db.AutoMigrate(&models.RFMT{}); err != nil {
 		log.Fatal("Failed to migrate RFMT:", err)
 	}
 
+	// Audit trail (exports, ...)
+	log.Println("📦 Creating audit_logs table...")
+	if err = db.AutoMigrate(&models.AuditLog{}); err != nil {
+		log.Fatal("Failed to migrate AuditLog:", err)
+	}
+
+	// Generated PDF reports
+	log.Println("📦 Creating reports table...")
+	if err = db.AutoMigrate(&models.Report{}); err != nil {
+		log.Fatal("Failed to migrate Report:", err)
+	}
+	if err = controllers.FailInterruptedReports(db); err != nil {
+		log.Println("⚠️  Failed to mark interrupted reports:", err)
+	}
+
+	// Scheduled jobs and their run history
+	log.Println("📦 Creating scheduled_jobs tables...")
+	if err = db.AutoMigrate(&models.ScheduledJob{}, &models.ScheduledJobRun{}); err != nil {
+		log.Fatal("Failed to migrate ScheduledJob:", err)
+	}
+
+	// Email notifications: officer addresses and delivery log
+	log.Println("📦 Creating notification tables...")
+	if err = db.AutoMigrate(&models.NotificationRecipient{}, &models.Notification{}); err != nil {
+		log.Fatal("Failed to migrate Notification:", err)
+	}
+
+	// Search indexes (FULLTEXT on names, B-tree on cif / norek / kode_uker)
+	if err = migrateSearchIndexes(db); err != nil {
+		log.Fatal("Failed to create search indexes:", err)
+	}
+
 	log.Println("✅ Database migration completed! All tables created with FK relationships.") // Create Fiber app
 	app := fiber.New(fiber.Config{
 		BodyLimit: 1024 * 1024 * 1024, // 1GB for large CSV files
@@ -93,14 +164,19 @@ func main() {
 		Format: "[${time}] ${status} - ${method} ${path} (${latency})\n",
 	}))
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata, Upload-Checksum",
+		AllowMethods:  "GET, POST, PUT, PATCH, HEAD, DELETE, OPTIONS",
+		ExposeHeaders: "Location, Tus-Resumable, Upload-Offset, Upload-Length",
 	}))
 
 	// Setup routes
 	routes.SetupRoutes(app)
 
+	// Background work: scheduled jobs and stale upload cleanup
+	controllers.StartScheduler(db)
+	controllers.StartUploadSweeper(db)
+
 	// Start server
 	port := os.Getenv("SERVER_PORT")
 	if port == "" {
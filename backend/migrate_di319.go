@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"pipeline-backend/controllers"
+	"pipeline-backend/models"
+	"pipeline-backend/money"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// di319AmountColumns - DI319 money columns and the definition they are migrated to
+var di319AmountColumns = []struct {
+	Name       string
+	Definition string
+	Nullable   bool
+}{
+	{"balance", "DECIMAL(20,2) NOT NULL", false},
+	{"aval_balance", "DECIMAL(20,2) NOT NULL DEFAULT 0", false},
+	{"avg_balance", "DECIMAL(20,2) NULL", true},
+}
+
+// di319PlainNumber - Values MySQL converts to DECIMAL on its own
+const di319PlainNumber = `^-?[0-9]+(\.[0-9]+)?$`
+
+// migrateDI319Amounts - Convert legacy BIGINT/VARCHAR balance columns to DECIMAL(20,2).
+// Plain numbers are converted by MySQL; formatted leftovers (1.234.567,89, Rp ..., (123))
+// are parsed row by row first, and anything unparseable becomes 0 / NULL.
+func migrateDI319Amounts(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.DI319{}) {
+		return nil
+	}
+
+	columnTypes, err := db.Migrator().ColumnTypes(&models.DI319{})
+	if err != nil {
+		return err
+	}
+	current := make(map[string]string, len(columnTypes))
+	for _, ct := range columnTypes {
+		current[ct.Name()] = strings.ToUpper(ct.DatabaseTypeName())
+	}
+
+	var modify []string
+	for _, col := range di319AmountColumns {
+		typeName, ok := current[col.Name]
+		if !ok || typeName == "DECIMAL" {
+			continue
+		}
+		if strings.Contains(typeName, "CHAR") || strings.Contains(typeName, "TEXT") {
+			converted, cleared, err := normalizeDI319AmountColumn(db, col.Name, col.Nullable)
+			if err != nil {
+				return fmt.Errorf("%s: %v", col.Name, err)
+			}
+			log.Printf("   %s: %d formatted values converted, %d unparseable values cleared", col.Name, converted, cleared)
+		}
+		modify = append(modify, fmt.Sprintf("MODIFY %s %s", col.Name, col.Definition))
+	}
+	if len(modify) == 0 {
+		return nil
+	}
+
+	log.Println("📦 Migrating di319 balance columns to DECIMAL(20,2)...")
+	return db.Exec("ALTER TABLE di319 " + strings.Join(modify, ", ")).Error
+}
+
+// normalizeDI319AmountColumn - Rewrite every non-plain value of a VARCHAR amount column as a plain decimal
+func normalizeDI319AmountColumn(db *gorm.DB, column string, nullable bool) (int, int, error) {
+	type row struct {
+		ID    uint
+		Value string
+	}
+
+	empty := interface{}("0")
+	if nullable {
+		empty = nil
+	}
+
+	var rows []row
+	err := db.Raw(fmt.Sprintf(
+		"SELECT id, %[1]s AS value FROM di319 WHERE %[1]s IS NOT NULL AND %[1]s NOT REGEXP ?", column),
+		di319PlainNumber).Scan(&rows).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	converted, cleared := 0, 0
+	update := fmt.Sprintf("UPDATE di319 SET %s = ? WHERE id = ?", column)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, r := range rows {
+			var value interface{}
+			if amount, err := money.Parse(r.Value); err == nil {
+				value = amount.String()
+				converted++
+			} else {
+				value = empty
+				cleared++
+			}
+			if err := tx.Exec(update, value, r.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return converted, cleared, err
+}
+
+// di319DropMetricColumns - Columns added for the persisted drop metrics
+var di319DropMetricColumns = []string{"DropAmount", "DropPct", "QualifyingRule"}
+
+// di319DropMetricIndexes - Indexes over the drop metric columns
+var di319DropMetricIndexes = []string{"idx_di319_drop_amount", "idx_di319_drop_pct", "idx_di319_qualifying_rule"}
+
+// migrateDI319DropMetrics - Add drop_amount / drop_pct / qualifying_rule and backfill existing rows.
+// Must run after migrateDI319Amounts, the backfill relies on DECIMAL balances.
+func migrateDI319DropMetrics(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.DI319{}) {
+		return nil
+	}
+
+	added := false
+	for _, field := range di319DropMetricColumns {
+		if db.Migrator().HasColumn(&models.DI319{}, field) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(&models.DI319{}, field); err != nil {
+			return err
+		}
+		added = true
+	}
+
+	if added {
+		log.Println("📦 Backfilling di319 drop metrics...")
+		err := db.Exec(`UPDATE di319 SET
+				drop_amount = avg_balance - balance,
+				drop_pct = GREATEST(LEAST(ROUND((avg_balance - balance) / avg_balance * 100, 2), ?), ?),
+				qualifying_rule = CASE WHEN (avg_balance - balance) * 2 >= avg_balance THEN ? ELSE '' END
+			WHERE avg_balance > 0`,
+			models.DI319MaxDropPct, -models.DI319MaxDropPct, models.DI319RuleBalanceDrop50).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, index := range di319DropMetricIndexes {
+		if db.Migrator().HasIndex(&models.DI319{}, index) {
+			continue
+		}
+		if err := db.Migrator().CreateIndex(&models.DI319{}, index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateDI319Snapshot - Create the full-snapshot table, range partitioned by periode.
+// It starts with a single catch-all partition; the importer splits off one partition per month.
+// MySQL requires the partitioning column in every unique key, hence PRIMARY KEY (id, periode).
+func migrateDI319Snapshot(db *gorm.DB) error {
+	if db.Migrator().HasTable(&models.DI319Snapshot{}) {
+		return nil
+	}
+
+	log.Println("📦 Creating di319_snapshot table (partitioned by periode)...")
+	return db.Exec(`CREATE TABLE di319_snapshot (
+		id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+		periode DATE NOT NULL,
+		main_branch VARCHAR(100) NOT NULL,
+		branch VARCHAR(5) NOT NULL,
+		cif VARCHAR(10) NOT NULL,
+		norek VARCHAR(20) NOT NULL,
+		type VARCHAR(50) NOT NULL,
+		nama VARCHAR(100) NOT NULL,
+		pn_pengelola VARCHAR(250) NOT NULL,
+		balance DECIMAL(20,2) NOT NULL,
+		aval_balance DECIMAL(20,2) NOT NULL DEFAULT 0,
+		avg_balance DECIMAL(20,2) NULL,
+		open_date DATE NOT NULL,
+		drop_amount DECIMAL(20,2) NULL,
+		drop_pct DECIMAL(9,2) NULL,
+		qualifying_rule VARCHAR(50) NOT NULL DEFAULT '',
+		PRIMARY KEY (id, periode),
+		KEY idx_di319_snapshot_periode_branch (periode, branch),
+		KEY idx_di319_snapshot_norek (norek),
+		KEY idx_di319_snapshot_drop_pct (drop_pct),
+		KEY idx_di319_snapshot_qualifying_rule (qualifying_rule)
+	) PARTITION BY RANGE COLUMNS(periode) (
+		PARTITION pmax VALUES LESS THAN (MAXVALUE)
+	)`).Error
+}
+
+// migrateDI319Summary - Create the pre-aggregated summary table and fill it when it starts out empty
+// (first run after upgrading). Imports keep it current afterwards.
+func migrateDI319Summary(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.DI319Summary{}); err != nil {
+		return err
+	}
+	if !db.Migrator().HasTable(&models.DI319{}) {
+		return nil
+	}
+
+	// Refreshes rebuild one periode at a time
+	if !db.Migrator().HasIndex(&models.DI319{}, "idx_di319_periode") {
+		log.Println("📦 Creating index idx_di319_periode on di319...")
+		if err := db.Exec("CREATE INDEX idx_di319_periode ON di319 (periode)").Error; err != nil {
+			return err
+		}
+	}
+
+	var summaryFilled, di319Filled bool
+	if err := db.Raw("SELECT EXISTS (SELECT 1 FROM di319_summary)").Row().Scan(&summaryFilled); err != nil {
+		return err
+	}
+	if summaryFilled {
+		return nil
+	}
+	if err := db.Raw("SELECT EXISTS (SELECT 1 FROM di319)").Row().Scan(&di319Filled); err != nil {
+		return err
+	}
+	if !di319Filled {
+		return nil
+	}
+
+	log.Println("📦 Building di319_summary from existing DI319 data...")
+	return controllers.RebuildDI319Summary(db)
+}
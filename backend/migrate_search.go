@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+	"pipeline-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// searchIndexes - Indexes behind GET /api/search. FULLTEXT covers names; the plain B-tree
+// indexes on cif / norek / kode_uker serve the prefix (LIKE 'x%') lookups for numbers.
+var searchIndexes = []struct {
+	Model interface{}
+	Table string
+	Name  string
+	DDL   string
+}{
+	{&models.DI319{}, "di319", "ft_di319_nama", "CREATE FULLTEXT INDEX ft_di319_nama ON di319 (nama)"},
+	{&models.DI319{}, "di319", "idx_di319_cif", "CREATE INDEX idx_di319_cif ON di319 (cif)"},
+	{&models.DI319{}, "di319", "idx_di319_norek", "CREATE INDEX idx_di319_norek ON di319 (norek)"},
+	{&models.Uker{}, "uker", "ft_uker_nama_uker", "CREATE FULLTEXT INDEX ft_uker_nama_uker ON uker (nama_uker)"},
+	{&models.Uker{}, "uker", "idx_uker_kode_uker", "CREATE INDEX idx_uker_kode_uker ON uker (kode_uker)"},
+}
+
+// migrateSearchIndexes - Create the search indexes on the pre-existing di319 / uker tables.
+// rfmts gets its FULLTEXT index from the model tags.
+func migrateSearchIndexes(db *gorm.DB) error {
+	for _, index := range searchIndexes {
+		if !db.Migrator().HasTable(index.Model) || db.Migrator().HasIndex(index.Model, index.Name) {
+			continue
+		}
+		log.Printf("📦 Creating search index %s on %s...", index.Name, index.Table)
+		if err := db.Exec(index.DDL).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
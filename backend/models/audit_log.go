@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AuditLog - Who did what with the data (exports, ...)
+type AuditLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"index:idx_audit_logs_user_id" json:"user_id"`
+	Username  string    `gorm:"type:varchar(50)" json:"username"`
+	Action    string    `gorm:"type:varchar(50);not null;index:idx_audit_logs_action" json:"action"` // e.g. export
+	Resource  string    `gorm:"type:varchar(100);not null" json:"resource"`                          // e.g. di319, stats
+	Details   string    `gorm:"type:text" json:"details"`                                            // query string, format, ...
+	Rows      int64     `json:"rows"`
+	CreatedAt time.Time `gorm:"index:idx_audit_logs_created_at" json:"created_at"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
@@ -1,23 +1,39 @@
 package models
 
-import "time"
+import (
+	"pipeline-backend/money"
+	"time"
+)
 
 type DI319 struct {
-	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Periode     time.Time `gorm:"type:date;not null" json:"periode"`
-	MainBranch  string    `gorm:"type:varchar(100);not null" json:"main_branch"`
-	Branch      string    `gorm:"type:varchar(5);not null;index:idx_di319_branch" json:"branch"`
-	CIF         string    `gorm:"type:varchar(10);not null" json:"cif"`
-	NoRek       string    `gorm:"column:norek;type:varchar(20);not null" json:"norek"`
-	Type        string    `gorm:"type:varchar(50);not null" json:"type"`
-	Nama        string    `gorm:"type:varchar(100);not null" json:"nama"`
-	PNPengelola string    `gorm:"type:varchar(250);not null" json:"pn_pengelola"`
-	Balance     int64     `gorm:"type:bigint;not null" json:"balance"`
-	AvalBalance string    `gorm:"type:varchar(20);not null" json:"aval_balance"`
-	AvgBalance  *string   `gorm:"type:varchar(20)" json:"avg_balance"`
-	OpenDate    time.Time `gorm:"type:date;not null" json:"open_date"`
+	ID          uint          `gorm:"primaryKey;autoIncrement" json:"id"`
+	Periode     time.Time     `gorm:"type:date;not null" json:"periode"`
+	MainBranch  string        `gorm:"type:varchar(100);not null" json:"main_branch"`
+	Branch      string        `gorm:"type:varchar(5);not null;index:idx_di319_branch" json:"branch"`
+	CIF         string        `gorm:"type:varchar(10);not null" json:"cif"`
+	NoRek       string        `gorm:"column:norek;type:varchar(20);not null" json:"norek"`
+	Type        string        `gorm:"type:varchar(50);not null" json:"type"`
+	Nama        string        `gorm:"type:varchar(100);not null" json:"nama"`
+	PNPengelola string        `gorm:"type:varchar(250);not null" json:"pn_pengelola"`
+	Balance     money.Amount  `gorm:"type:decimal(20,2);not null" json:"balance"`
+	AvalBalance money.Amount  `gorm:"type:decimal(20,2);not null" json:"aval_balance"`
+	AvgBalance  *money.Amount `gorm:"type:decimal(20,2)" json:"avg_balance"`
+	OpenDate    time.Time     `gorm:"type:date;not null" json:"open_date"`
+
+	// Drop metrics computed at import (NULL without a positive average balance)
+	DropAmount     *money.Amount `gorm:"type:decimal(20,2);index:idx_di319_drop_amount" json:"drop_amount"`
+	DropPct        *float64      `gorm:"type:decimal(9,2);index:idx_di319_drop_pct" json:"drop_pct"`
+	QualifyingRule string        `gorm:"type:varchar(50);not null;default:'';index:idx_di319_qualifying_rule" json:"qualifying_rule"`
 }
 
+// DI319MaxDropPct - Largest |drop_pct| the DECIMAL(9,2) column holds
+const DI319MaxDropPct = 9999999.99
+
+// DI319 qualifying rules
+const (
+	DI319RuleBalanceDrop50 = "balance_drop_50pct" // balance fell 50% or more below the average balance
+)
+
 func (DI319) TableName() string {
 	return "di319"
 }
@@ -0,0 +1,11 @@
+package models
+
+// DI319Snapshot - Every account of a DI319 extract, not just the drop candidates.
+// Partitioned by periode (see migrateDI319Snapshot); candidates have a non-empty QualifyingRule.
+type DI319Snapshot struct {
+	DI319
+}
+
+func (DI319Snapshot) TableName() string {
+	return "di319_snapshot"
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"pipeline-backend/money"
+	"time"
+)
+
+// DI319Summary - DI319 pre-aggregated per periode × branch × type × PN pengelola (main_branch rides along
+// in the key). Rebuilt one periode at a time after each import; dashboards and analytics read it instead
+// of scanning di319. Averages are kept as sum + count so they stay exact when groups are combined.
+type DI319Summary struct {
+	Periode     time.Time `gorm:"type:date;primaryKey" json:"periode"`
+	Branch      string    `gorm:"type:varchar(5);primaryKey" json:"branch"`
+	MainBranch  string    `gorm:"type:varchar(100);primaryKey" json:"main_branch"`
+	Type        string    `gorm:"type:varchar(50);primaryKey" json:"type"`
+	PNPengelola string    `gorm:"type:varchar(250);primaryKey" json:"pn_pengelola"`
+
+	RowCount       int64        `gorm:"not null" json:"row_count"`
+	SumBalance     money.Amount `gorm:"type:decimal(24,2);not null" json:"sum_balance"`
+	SumAvalBalance money.Amount `gorm:"type:decimal(24,2);not null" json:"sum_aval_balance"`
+	SumAvgBalance  money.Amount `gorm:"type:decimal(24,2);not null" json:"sum_avg_balance"`
+	DropCount      int64        `gorm:"not null" json:"drop_count"`
+	SumDrop        money.Amount `gorm:"type:decimal(24,2);not null" json:"sum_drop"` // positive drops only
+	SumDropPct     float64      `gorm:"type:decimal(20,2);not null" json:"sum_drop_pct"`
+	DropPctCount   int64        `gorm:"not null" json:"drop_pct_count"` // rows with a drop_pct
+	MaxDropPct     *float64     `gorm:"type:decimal(9,2)" json:"max_drop_pct"`
+	RefreshedAt    time.Time    `gorm:"not null" json:"refreshed_at"`
+}
+
+func (DI319Summary) TableName() string {
+	return "di319_summary"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// ImportProfile - Column alias and format settings for one DI319 report layout
+type ImportProfile struct {
+	ID                uint                `gorm:"primarykey" json:"id"`
+	Name              string              `gorm:"type:varchar(100);uniqueIndex;not null" json:"name"`
+	Description       string              `gorm:"type:varchar(255)" json:"description"`
+	Aliases           map[string][]string `gorm:"type:text;serializer:json" json:"aliases"`      // logical field -> header names
+	DateFormats       map[string][]string `gorm:"type:text;serializer:json" json:"date_formats"` // logical field -> formats, e.g. dd/MM/yyyy
+	ThousandSeparator string              `gorm:"type:varchar(1)" json:"thousand_separator"`
+	DecimalSeparator  string              `gorm:"type:varchar(1);not null;default:'.'" json:"decimal_separator"`
+	IsActive          bool                `gorm:"not null" json:"is_active"`
+	CreatedAt         time.Time           `json:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at"`
+}
+
+func (ImportProfile) TableName() string {
+	return "import_profiles"
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// NotificationRecipient - Where an RMFT officer (by PN, e.g. "PN123456") receives notifications
+type NotificationRecipient struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	PN          string     `gorm:"type:varchar(50);not null;uniqueIndex:idx_notification_recipients_pn" json:"pn"`
+	Email       string     `gorm:"type:varchar(255);not null" json:"email"`
+	OptOut      bool       `gorm:"not null" json:"opt_out"`
+	OptOutAt    *time.Time `json:"opt_out_at"`
+	OptOutToken string     `gorm:"type:varchar(64);not null;uniqueIndex:idx_notification_recipients_token" json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (NotificationRecipient) TableName() string {
+	return "notification_recipients"
+}
+
+// Notification - One digest for one officer and periode, with its delivery status
+type Notification struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Kind      string     `gorm:"type:varchar(50);not null;uniqueIndex:idx_notifications_digest,priority:1" json:"kind"`
+	Periode   time.Time  `gorm:"type:date;not null;uniqueIndex:idx_notifications_digest,priority:2" json:"periode"`
+	PN        string     `gorm:"type:varchar(50);not null;uniqueIndex:idx_notifications_digest,priority:3" json:"pn"`
+	Email     string     `gorm:"type:varchar(255);not null;default:''" json:"email"`
+	Subject   string     `gorm:"type:varchar(255);not null;default:''" json:"subject"`
+	Accounts  int64      `gorm:"not null;default:0" json:"accounts"`
+	Status    string     `gorm:"type:varchar(20);not null;index:idx_notifications_status" json:"status"`
+	Error     string     `gorm:"type:text" json:"error,omitempty"`
+	Attempts  int        `gorm:"not null;default:0" json:"attempts"`
+	SentAt    *time.Time `json:"sent_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// Notification statuses
+const (
+	NotificationPending   = "pending"
+	NotificationSending   = "sending" // claimed by the run delivering it
+	NotificationSent      = "sent"
+	NotificationFailed    = "failed"
+	NotificationOptedOut  = "opted_out"  // the officer unsubscribed
+	NotificationNoAddress = "no_address" // no email on file for the PN
+)
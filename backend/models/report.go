@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Report - A generated PDF report, kept on local disk for download
+type Report struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Template    string     `gorm:"type:varchar(50);not null" json:"template"`
+	Uker        string     `gorm:"type:varchar(5);not null;index:idx_reports_uker" json:"uker"`
+	Region      string     `gorm:"type:varchar(5);not null;default:''" json:"region"`
+	Periode     time.Time  `gorm:"type:date;not null" json:"periode"`
+	BatchID     string     `gorm:"type:varchar(36);not null;index:idx_reports_batch" json:"batch_id"` // shared by a bulk run
+	Status      string     `gorm:"type:varchar(20);not null;default:'queued'" json:"status"`          // queued, running, done, failed
+	Error       string     `gorm:"type:text" json:"error,omitempty"`
+	Filename    string     `gorm:"type:varchar(255);not null;default:''" json:"filename"`
+	Path        string     `gorm:"type:varchar(500);not null;default:''" json:"-"`
+	Size        int64      `gorm:"type:bigint;not null;default:0" json:"size"`
+	UserID      uint       `gorm:"index" json:"user_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+// Report statuses
+const (
+	ReportQueued  = "queued"
+	ReportRunning = "running"
+	ReportDone    = "done"
+	ReportFailed  = "failed"
+)
+
+func (Report) TableName() string {
+	return "reports"
+}
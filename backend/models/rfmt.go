@@ -11,7 +11,7 @@ type RFMT struct {
 	UkerID              *int           `gorm:"type:int;index:idx_rfmt_uker_id" json:"uker_id,omitempty"`
 	UkerRelation        *Uker          `gorm:"foreignKey:UkerID;references:ID;constraint:OnUpdate:RESTRICT,OnDelete:SET NULL" json:"uker_relation,omitempty"`
 	PN                  string         `gorm:"type:varchar(50);index:idx_rfmt_pn;not null" json:"pn"`
-	NamaLengkap         string         `gorm:"type:varchar(255)" json:"nama_lengkap"`
+	NamaLengkap         string         `gorm:"type:varchar(255);index:ft_rfmt_nama_lengkap,class:FULLTEXT" json:"nama_lengkap"`
 	JG                  string         `gorm:"type:varchar(50)" json:"jg"`
 	ESGDESC             string         `gorm:"type:varchar(100)" json:"esgdesc"`
 	Kanca               string         `gorm:"type:varchar(100)" json:"kanca"`
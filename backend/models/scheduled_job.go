@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// ScheduledJob - A task the backend runs on a cron timetable
+type ScheduledJob struct {
+	ID                uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name              string     `gorm:"type:varchar(100);not null;uniqueIndex:idx_scheduled_jobs_name" json:"name"`
+	Kind              string     `gorm:"type:varchar(30);not null" json:"kind"`  // report, export, recovery, retention
+	Cron              string     `gorm:"type:varchar(100);not null" json:"cron"` // 5-field cron or @daily etc., optional CRON_TZ= prefix
+	Params            string     `gorm:"type:text" json:"params"`                // JSON, depends on kind
+	Enabled           bool       `gorm:"not null" json:"enabled"`
+	MaxRetries        int        `gorm:"not null" json:"max_retries"`             // extra attempts after a failed run
+	RetryDelayMinutes int        `gorm:"not null" json:"retry_delay_minutes"`     // multiplied by the attempt number
+	RetryAttempt      int        `gorm:"not null;default:0" json:"retry_attempt"` // failed attempts of the current run
+	NextRunAt         *time.Time `gorm:"index:idx_scheduled_jobs_next_run" json:"next_run_at"`
+	LastRunAt         *time.Time `json:"last_run_at"`
+	LastStatus        string     `gorm:"type:varchar(20);not null;default:''" json:"last_status"`
+	CreatedBy         uint       `json:"created_by"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+func (ScheduledJob) TableName() string {
+	return "scheduled_jobs"
+}
+
+// ScheduledJobRun - One attempt of a scheduled job
+type ScheduledJobRun struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	JobID       uint       `gorm:"not null;index:idx_scheduled_job_runs_job" json:"job_id"`
+	Trigger     string     `gorm:"column:run_trigger;type:varchar(20);not null" json:"trigger"` // schedule, retry, manual
+	Attempt     int        `gorm:"not null;default:1" json:"attempt"`
+	Status      string     `gorm:"type:varchar(20);not null" json:"status"` // running, success, failed
+	Message     string     `gorm:"type:text" json:"message"`
+	Output      string     `gorm:"type:varchar(500);not null;default:''" json:"-"` // file kept for download
+	OutputName  string     `gorm:"type:varchar(255);not null;default:''" json:"output_name"`
+	Rows        int64      `gorm:"not null;default:0" json:"rows"`
+	Instance    string     `gorm:"type:varchar(100);not null;default:''" json:"instance"` // backend instance executing the run
+	HeartbeatAt *time.Time `json:"heartbeat_at"`                                          // refreshed while the run is alive
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+}
+
+func (ScheduledJobRun) TableName() string {
+	return "scheduled_job_runs"
+}
+
+// Scheduled job run statuses
+const (
+	JobRunRunning = "running"
+	JobRunSuccess = "success"
+	JobRunFailed  = "failed"
+)
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Upload - A resumable (chunked) file upload staged on local disk
+type Upload struct {
+	ID             string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Filename       string    `gorm:"type:varchar(255);not null" json:"filename"`
+	Size           int64     `gorm:"type:bigint;not null" json:"size"`
+	Offset         int64     `gorm:"column:upload_offset;type:bigint;not null;default:0" json:"offset"`
+	Status         string    `gorm:"type:varchar(20);not null;default:'uploading'" json:"status"` // uploading, completed, corrupt
+	ExpectedSHA256 string    `gorm:"column:expected_sha256;type:varchar(64)" json:"expected_sha256,omitempty"`
+	SHA256         string    `gorm:"column:sha256;type:varchar(64)" json:"sha256,omitempty"`
+	Path           string    `gorm:"type:varchar(500);not null" json:"-"`
+	UserID         uint      `gorm:"index" json:"user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (Upload) TableName() string {
+	return "uploads"
+}
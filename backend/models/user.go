@@ -15,6 +15,7 @@ type User struct {
 	Email     string         `gorm:"type:varchar(100);uniqueIndex" json:"email"`
 	Role      string         `gorm:"type:varchar(20);not null;default:'user'" json:"role"` // admin, user
 	IsActive  bool           `gorm:"default:true" json:"is_active"`
+	DataScope string         `gorm:"type:varchar(500);not null;default:''" json:"data_scope"` // comma separated branch / main_branch codes, empty = all data
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
@@ -0,0 +1,278 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Scale - Amounts are kept in minor units (sen), two decimal places
+const Scale = 100
+
+// ErrEmpty - The input held no amount ("", "-", "N/A")
+var ErrEmpty = errors.New("empty amount")
+
+// Amount - An exact monetary amount in minor units, stored as DECIMAL(20,2)
+type Amount int64
+
+// FromMinor - Build an amount from minor units
+func FromMinor(minor int64) Amount {
+	return Amount(minor)
+}
+
+// FromInt - Build an amount from whole rupiah
+func FromInt(major int64) Amount {
+	return Amount(major * Scale)
+}
+
+// Minor - The amount in minor units
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+// Float - Approximate float value, for ratios and display only
+func (a Amount) Float() float64 {
+	return float64(a) / Scale
+}
+
+// String - Plain decimal representation, e.g. -1234567.89
+func (a Amount) String() string {
+	sign := ""
+	v := int64(a)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/Scale, v%Scale)
+}
+
+// Parse - Parse a formatted amount, auto-detecting the decimal separator.
+// Handles 1,234,567.89 / 1.234.567,89 / Rp 1.234.567 / (1,234.50) / -Rp 1.234,00 / 1234- .
+// A single separator followed by exactly three digits is read as grouping.
+func Parse(s string) (Amount, error) {
+	return ParseHint(s, 0)
+}
+
+// ParseHint - Like Parse, but a lone separator followed by three digits is treated as the
+// decimal point when it equals decimalHint ('.' or ','), e.g. when an import profile says so
+func ParseHint(s string, decimalHint rune) (Amount, error) {
+	negative, digits, err := normalize(s, decimalHint)
+	if err != nil {
+		return 0, err
+	}
+
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" {
+		whole = "0"
+	}
+
+	major, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || major > math.MaxInt64/Scale-1 {
+		return 0, fmt.Errorf("amount out of range: %q", s)
+	}
+
+	// Round half away from zero to two decimals
+	minor := int64(0)
+	for i := 0; i < 2; i++ {
+		minor *= 10
+		if i < len(frac) {
+			minor += int64(frac[i] - '0')
+		}
+	}
+	if len(frac) > 2 && frac[2] >= '5' {
+		minor++
+	}
+
+	v := major*Scale + minor
+	if negative {
+		v = -v
+	}
+	return Amount(v), nil
+}
+
+// normalize - Reduce a formatted amount to sign + "digits[.digits]"
+func normalize(s string, decimalHint rune) (bool, string, error) {
+	s = strings.TrimSpace(strings.Trim(s, `"'`))
+	negative, signed := false, false
+
+	// Sign, parentheses and currency come in any order around the number: -Rp 1.234,00 / (Rp 1.234) /
+	// Rp -1.234 / 1234- / 1.234 IDR. Peel them off until only the number is left; one sign at most.
+	for {
+		s = strings.TrimSpace(s)
+		upper := strings.ToUpper(s)
+		switch {
+		case strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")"),
+			strings.HasPrefix(s, "-"), strings.HasSuffix(s, "-"), strings.HasPrefix(s, "+"):
+			if signed {
+				return false, "", fmt.Errorf("invalid amount: %q", s)
+			}
+			signed = true
+			switch {
+			case strings.HasPrefix(s, "("):
+				negative, s = true, s[1:len(s)-1]
+			case strings.HasPrefix(s, "-"):
+				negative, s = true, s[1:]
+			case strings.HasSuffix(s, "-"):
+				negative, s = true, s[:len(s)-1]
+			default:
+				s = s[1:]
+			}
+			continue
+		}
+		if currency := currencyAffix(upper); currency != "" {
+			if strings.HasPrefix(upper, currency) {
+				s = s[len(currency):]
+			} else {
+				s = s[:len(s)-len(currency)]
+			}
+			continue
+		}
+		break
+	}
+
+	// Drop spaces (incl. NBSP) and apostrophes used as digit grouping
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\'' {
+			return -1
+		}
+		return r
+	}, s)
+
+	if s == "" || strings.EqualFold(s, "N/A") {
+		return false, "", ErrEmpty
+	}
+
+	for _, r := range s {
+		if !unicode.IsDigit(r) && r != '.' && r != ',' {
+			return false, "", fmt.Errorf("invalid amount: %q", s)
+		}
+	}
+
+	decimal := decimalSeparator(s, decimalHint)
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == decimal:
+			b.WriteByte('.')
+		case r == '.' || r == ',':
+			// grouping separator
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	digits := b.String()
+	if strings.Count(digits, ".") > 1 {
+		return false, "", fmt.Errorf("invalid amount: %q", s)
+	}
+	return negative, digits, nil
+}
+
+// currencyAffix - The currency marker (Rp, Rp., IDR) s starts or ends with, "" when there is none
+func currencyAffix(upper string) string {
+	for _, currency := range []string{"RP.", "RP", "IDR"} {
+		if strings.HasPrefix(upper, currency) || strings.HasSuffix(upper, currency) {
+			return currency
+		}
+	}
+	return ""
+}
+
+// decimalSeparator - Which of '.' / ',' is the decimal point in s (0 when there is none)
+func decimalSeparator(s string, decimalHint rune) rune {
+	lastDot := strings.LastIndexByte(s, '.')
+	lastComma := strings.LastIndexByte(s, ',')
+
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		// Both present: whichever comes last is the decimal point
+		if lastDot > lastComma {
+			return '.'
+		}
+		return ','
+	case lastDot < 0 && lastComma < 0:
+		return 0
+	}
+
+	sep, last := '.', lastDot
+	if lastComma >= 0 {
+		sep, last = ',', lastComma
+	}
+
+	// Repeated separator can only be grouping: 1.234.567
+	if strings.Count(s, string(sep)) > 1 {
+		return 0
+	}
+	// 1,5 / 1.25 - not three digits after it, so it must be decimal
+	if len(s)-last-1 != 3 {
+		return sep
+	}
+	// 1.234 / 1,234 - ambiguous, grouping unless the hint says otherwise
+	if sep == decimalHint {
+		return sep
+	}
+	return 0
+}
+
+// Scan - sql.Scanner for DECIMAL, BIGINT and legacy VARCHAR columns
+func (a *Amount) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case int64:
+		*a = FromInt(v)
+		return nil
+	case float64:
+		*a = Amount(math.Round(v * Scale))
+		return nil
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	}
+	return fmt.Errorf("money: cannot scan %T", value)
+}
+
+func (a *Amount) scanString(s string) error {
+	if strings.TrimSpace(s) == "" {
+		*a = 0
+		return nil
+	}
+	// Database values always use '.' as the decimal point
+	parsed, err := ParseHint(s, '.')
+	if err != nil {
+		return fmt.Errorf("money: %v", err)
+	}
+	*a = parsed
+	return nil
+}
+
+// Value - driver.Valuer, sent as a plain decimal string
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// MarshalJSON - Encode as a JSON number with two decimals
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON - Accept a JSON number or a formatted string
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		*a = 0
+		return nil
+	}
+	parsed, err := ParseHint(s, '.')
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
@@ -0,0 +1,114 @@
+package money
+
+import "testing"
+
+func TestParseHint(t *testing.T) {
+	tests := []struct {
+		in   string
+		hint rune
+		want Amount
+		err  bool
+	}{
+		// Separator detection
+		{"1,234,567.89", 0, 123456789, false},
+		{"1.234.567,89", 0, 123456789, false},
+		{"1234567", 0, 123456700, false},
+		{"1,5", 0, 150, false},
+		{"1.25", 0, 125, false},
+		{"1.234", 0, 123400, false},
+		{"1,234", 0, 123400, false},
+
+		// A lone separator before three digits follows the hint
+		{"1.234", '.', 123, false},
+		{"1234.500", '.', 123450, false},
+		{"1234,500", ',', 123450, false},
+		{"1234.500", ',', 123450000, false},
+		{"1.234.500", '.', 123450000, false}, // repeated, so grouping whatever the hint
+
+		// Rounding half away from zero
+		{"1.255", '.', 126, false},
+		{"-1.255", '.', -126, false},
+		{"1.2549", 0, 125, false},
+
+		// Signs, currencies, spacing
+		{"(1.234,50)", 0, -123450, false},
+		{"1234-", 0, -123400, false},
+		{"+12", 0, 1200, false},
+		{"Rp 1.234.567", 0, 123456700, false},
+		{"Rp. 1.234.567,5", 0, 123456750, false},
+		{"1 234 567,89", 0, 123456789, false},
+		{"1'234.50", 0, 123450, false},
+		{"IDR 10", 0, 1000, false},
+		{`"5,00"`, 0, 500, false},
+		{"-Rp 1.234,00", 0, -123400, false},
+		{"(Rp 1.234)", 0, -123400, false},
+		{"(Rp 1.234)", ',', -123400, false},
+		{"Rp (1.234,50)", 0, -123450, false},
+		{"Rp -1.234,50", 0, -123450, false},
+		{"-IDR 10", 0, -1000, false},
+		{"1.234,50 Rp-", 0, -123450, false},
+		{"+Rp. 7", 0, 700, false},
+
+		// Errors
+		{"", 0, 0, true},
+		{"-", 0, 0, true},
+		{"N/A", 0, 0, true},
+		{"12abc", 0, 0, true},
+		{"1.2.3,4,5", 0, 0, true},
+		{"99999999999999999999", 0, 0, true},
+		{"--1", 0, 0, true},
+		{"-(1)", 0, 0, true},
+		{"-Rp -1", 0, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseHint(tt.in, tt.hint)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseHint(%q, %q) error = %v, want error %v", tt.in, tt.hint, err, tt.err)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("ParseHint(%q, %q) = %s, want %s", tt.in, tt.hint, got, tt.want)
+		}
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	for _, in := range []string{"", "  ", "-", "N/A", "Rp"} {
+		if _, err := Parse(in); err != ErrEmpty {
+			t.Errorf("Parse(%q) error = %v, want ErrEmpty", in, err)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	tests := []struct {
+		in   Amount
+		want string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{123456789, "1234567.89"},
+		{-150, "-1.50"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Amount(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestAmountScanRoundTrip(t *testing.T) {
+	for _, a := range []Amount{0, 1, -1, 123456789, -99999} {
+		v, err := a.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back Amount
+		if err := back.Scan([]byte(v.(string))); err != nil {
+			t.Fatalf("Scan(%q): %v", v, err)
+		}
+		if back != a {
+			t.Errorf("round trip %s -> %q -> %s", a, v, back)
+		}
+	}
+}
@@ -1,35 +1,35 @@
--- Optimization for Dashboard Performance
--- Run this script to speed up dashboard queries
+-- Dashboard performance: rebuild the DI319 summary table
+-- The stats and analytics endpoints read di319_summary (one row per periode x branch x type x PN).
+-- The backend creates and fills it on startup and refreshes the imported periodes after each import;
+-- run this script only to rebuild it by hand, e.g. after editing di319 directly.
 
 USE pipeline_db;
 
--- Drop existing indexes if any (to recreate with better structure)
-DROP INDEX IF EXISTS idx_strategy ON pipelines;
-DROP INDEX IF EXISTS idx_segment ON pipelines;
-DROP INDEX IF EXISTS idx_pipeline ON pipelines;
-DROP INDEX IF EXISTS idx_proyeksi ON pipelines;
-DROP INDEX IF EXISTS idx_created_at ON pipelines;
+-- Index used when refreshing one periode
+-- (skip if it exists: the backend creates idx_di319_periode on startup)
+-- CREATE INDEX idx_di319_periode ON di319 (periode);
 
--- Create optimized indexes for dashboard queries
--- These indexes will speed up COUNT, SUM, and GROUP BY operations
+START TRANSACTION;
 
--- Index for strategy statistics (COUNT and SUM grouped by strategy)
-CREATE INDEX idx_strategy_stats ON pipelines(deleted_at, strategy, proyeksi);
+DELETE FROM di319_summary;
 
--- Index for segment statistics (COUNT and SUM grouped by segment)
-CREATE INDEX idx_segment_stats ON pipelines(deleted_at, segment, proyeksi);
+INSERT INTO di319_summary (periode, branch, main_branch, type, pn_pengelola,
+    row_count, sum_balance, sum_aval_balance, sum_avg_balance, drop_count, sum_drop, sum_drop_pct, drop_pct_count,
+    max_drop_pct, refreshed_at)
+SELECT periode, branch, main_branch, type, pn_pengelola,
+    COUNT(*), COALESCE(SUM(balance), 0), COALESCE(SUM(aval_balance), 0), COALESCE(SUM(avg_balance), 0),
+    COALESCE(SUM(qualifying_rule <> ''), 0), COALESCE(SUM(CASE WHEN drop_amount > 0 THEN drop_amount END), 0),
+    COALESCE(SUM(drop_pct), 0), COUNT(drop_pct), MAX(drop_pct), NOW()
+FROM di319
+GROUP BY periode, branch, main_branch, type, pn_pengelola;
 
--- Index for general queries (pagination, filtering)
-CREATE INDEX idx_general ON pipelines(deleted_at, created_at);
+COMMIT;
 
--- Composite index for total count and sum (covers deleted_at and proyeksi)
-CREATE INDEX idx_total_stats ON pipelines(deleted_at, proyeksi);
+-- Analyze tables to update statistics
+ANALYZE TABLE di319, di319_summary;
 
--- Analyze table to update statistics
-ANALYZE TABLE pipelines;
-
--- Show all indexes
-SHOW INDEX FROM pipelines;
-
--- Show table status
-SHOW TABLE STATUS LIKE 'pipelines';
+-- Summary size per periode
+SELECT periode, COUNT(*) AS summary_rows, SUM(row_count) AS di319_rows
+FROM di319_summary
+GROUP BY periode
+ORDER BY periode DESC;
@@ -0,0 +1,46 @@
+package pagination
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Count modes for list endpoints (?count=)
+const (
+	CountExact    = "exact"    // COUNT(*) honouring the filters
+	CountEstimate = "estimate" // table statistics, ignores filters, cheap on large tables
+	CountNone     = "none"     // no total
+)
+
+// ParseCountMode - Validate ?count=, falling back to def when empty
+func ParseCountMode(raw, def string) (string, error) {
+	switch raw {
+	case "":
+		return def, nil
+	case CountExact, CountEstimate, CountNone:
+		return raw, nil
+	}
+	return "", fmt.Errorf("invalid count: %s (expected exact, estimate or none)", raw)
+}
+
+// Total - Row total for a count mode. Returns nil for CountNone and whether the value is an estimate.
+// query must carry the filters but no ORDER BY / LIMIT.
+func Total(db *gorm.DB, query *gorm.DB, table, mode string) (*int64, bool, error) {
+	var total int64
+	switch mode {
+	case CountExact:
+		if err := query.Count(&total).Error; err != nil {
+			return nil, false, err
+		}
+		return &total, false, nil
+	case CountEstimate:
+		err := db.Raw("SELECT COALESCE(TABLE_ROWS, 0) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", table).
+			Scan(&total).Error
+		if err != nil {
+			return nil, false, err
+		}
+		return &total, true, nil
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,181 @@
+package pagination
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidCursor - The cursor is malformed or was issued for a different sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor - Position after the last row of a page: the sort key value and the row ID.
+// Clients treat it as opaque; it is base64url encoded JSON.
+type Cursor struct {
+	Sort  string  `json:"s"`
+	Desc  bool    `json:"d"`
+	Value *string `json:"v,omitempty"` // nil when the sort column was NULL (or the sort is by ID only)
+	ID    uint64  `json:"i"`
+}
+
+// Encode - Opaque string form of the cursor
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor - Parse a cursor produced by Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Keyset - A sort order that can be paged by key: an optional column plus the ID tie-breaker.
+// Nullable columns sort NULLs last in both directions, matching the offset listings.
+type Keyset struct {
+	Sort     string // public sort name, stored in the cursor
+	Column   string // "" to page by ID alone
+	Nullable bool
+	Desc     bool
+	IDColumn string // defaults to "id"
+}
+
+func (k Keyset) idColumn() string {
+	if k.IDColumn == "" {
+		return "id"
+	}
+	return k.IDColumn
+}
+
+// OrderSQL - ORDER BY clause for the keyset
+func (k Keyset) OrderSQL() string {
+	dir := "ASC"
+	if k.Desc {
+		dir = "DESC"
+	}
+	if k.Column == "" {
+		return fmt.Sprintf("%s %s", k.idColumn(), dir)
+	}
+	order := fmt.Sprintf("%s %s, %s %s", k.Column, dir, k.idColumn(), dir)
+	if k.Nullable {
+		order = fmt.Sprintf("%s IS NULL, %s", k.Column, order)
+	}
+	return order
+}
+
+// Apply - Restrict the query to rows after the cursor, order it and fetch one extra row to detect more pages
+func (k Keyset) Apply(db *gorm.DB, cursor *Cursor, limit int) (*gorm.DB, error) {
+	db = db.Order(k.OrderSQL()).Limit(limit + 1)
+	if cursor == nil {
+		return db, nil
+	}
+	if cursor.Sort != k.Sort || cursor.Desc != k.Desc {
+		return nil, ErrInvalidCursor
+	}
+
+	cmp := ">"
+	if k.Desc {
+		cmp = "<"
+	}
+	id := k.idColumn()
+
+	switch {
+	case k.Column == "":
+		return db.Where(fmt.Sprintf("%s %s ?", id, cmp), cursor.ID), nil
+	case cursor.Value == nil && k.Nullable:
+		// Already in the trailing NULL block
+		return db.Where(fmt.Sprintf("%s IS NULL AND %s %s ?", k.Column, id, cmp), cursor.ID), nil
+	case cursor.Value == nil:
+		return nil, ErrInvalidCursor
+	}
+
+	where := fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND %[3]s %[2]s ?))", k.Column, cmp, id)
+	if k.Nullable {
+		where = fmt.Sprintf("(%s OR %s IS NULL)", where, k.Column)
+	}
+	return db.Where(where, *cursor.Value, *cursor.Value, cursor.ID), nil
+}
+
+// Trim - Drop the extra row fetched by Apply and build the cursor for the next page ("" on the last page)
+func Trim[T any](db *gorm.DB, rows []T, k Keyset, limit int) ([]T, string, error) {
+	if len(rows) <= limit {
+		return rows, "", nil
+	}
+	rows = rows[:limit]
+
+	sch, err := parseSchema(db, &rows[0])
+	if err != nil {
+		return nil, "", err
+	}
+	last := reflect.ValueOf(&rows[limit-1]).Elem()
+
+	cursor := Cursor{Sort: k.Sort, Desc: k.Desc}
+	idValue, err := columnValue(sch, last, k.idColumn())
+	if err != nil {
+		return nil, "", err
+	}
+	if idValue == nil {
+		return nil, "", fmt.Errorf("pagination: %s is NULL", k.idColumn())
+	}
+	if _, err := fmt.Sscan(*idValue, &cursor.ID); err != nil {
+		return nil, "", fmt.Errorf("pagination: %s is not numeric", k.idColumn())
+	}
+	if k.Column != "" {
+		if cursor.Value, err = columnValue(sch, last, k.Column); err != nil {
+			return nil, "", err
+		}
+	}
+	return rows, cursor.Encode(), nil
+}
+
+var schemaCache sync.Map
+
+func parseSchema(db *gorm.DB, model interface{}) (*schema.Schema, error) {
+	return schema.Parse(model, &schemaCache, db.NamingStrategy)
+}
+
+// columnValue - A row's value for a column, as the string MySQL compares it with (nil for NULL)
+func columnValue(sch *schema.Schema, row reflect.Value, column string) (*string, error) {
+	field := sch.LookUpField(column)
+	if field == nil {
+		return nil, fmt.Errorf("pagination: unknown column %s", column)
+	}
+
+	value, _ := field.ValueOf(context.Background(), row)
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		value = v.Elem().Interface()
+	}
+	if valuer, ok := value.(driver.Valuer); ok {
+		if value, _ = valuer.Value(); value == nil {
+			return nil, nil
+		}
+	}
+
+	var s string
+	switch v := value.(type) {
+	case time.Time:
+		s = v.Format("2006-01-02 15:04:05.999999")
+	default:
+		s = fmt.Sprint(v)
+	}
+	return &s, nil
+}
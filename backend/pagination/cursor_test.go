@@ -0,0 +1,184 @@
+package pagination
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+type testRow struct {
+	ID        uint
+	Name      string
+	Score     *int64
+	CreatedAt time.Time
+}
+
+// dryRunDB - Renders MySQL statements without a server
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user:pass@tcp(localhost:3306)/test", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, c := range []Cursor{
+		{Sort: "id", ID: 1},
+		{Sort: "name", Desc: true, Value: strPtr("Bank \"A\" / ü"), ID: 42},
+		{Sort: "score", Value: nil, ID: 18446744073709551615},
+		{Sort: "created_at", Value: strPtr("2025-01-31 10:00:00.5"), ID: 7},
+	} {
+		encoded := c.Encode()
+		if strings.ContainsAny(encoded, "+/=") {
+			t.Errorf("cursor %q is not URL safe", encoded)
+		}
+		got, err := DecodeCursor(encoded)
+		if err != nil {
+			t.Fatalf("DecodeCursor(%q): %v", encoded, err)
+		}
+		if got.Sort != c.Sort || got.Desc != c.Desc || got.ID != c.ID ||
+			(got.Value == nil) != (c.Value == nil) || (got.Value != nil && *got.Value != *c.Value) {
+			t.Errorf("round trip %+v -> %+v", c, *got)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	for _, s := range []string{"", "!!!", "bm90IGpzb24", "eyJpIjoiYSJ9"} { // "not json", {"i":"a"}
+		if _, err := DecodeCursor(s); err != ErrInvalidCursor {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
+
+func TestKeysetOrderSQL(t *testing.T) {
+	tests := []struct {
+		keyset Keyset
+		want   string
+	}{
+		{Keyset{}, "id ASC"},
+		{Keyset{Desc: true, IDColumn: "d.id"}, "d.id DESC"},
+		{Keyset{Column: "name"}, "name ASC, id ASC"},
+		{Keyset{Column: "score", Nullable: true}, "score IS NULL, score ASC, id ASC"},
+		{Keyset{Column: "score", Nullable: true, Desc: true}, "score IS NULL, score DESC, id DESC"},
+	}
+	for _, tt := range tests {
+		if got := tt.keyset.OrderSQL(); got != tt.want {
+			t.Errorf("%+v.OrderSQL() = %q, want %q", tt.keyset, got, tt.want)
+		}
+	}
+}
+
+func TestKeysetApply(t *testing.T) {
+	db := dryRunDB(t)
+	score := Keyset{Sort: "score", Column: "score", Nullable: true, Desc: true}
+
+	tests := []struct {
+		name    string
+		keyset  Keyset
+		cursor  *Cursor
+		want    string // expected WHERE fragment, "" for none
+		wantErr bool
+	}{
+		{name: "first page", keyset: score},
+		{name: "by id", keyset: Keyset{Sort: "id"}, cursor: &Cursor{Sort: "id", ID: 5}, want: "id > 5"},
+		{
+			name: "nullable value, NULLs still ahead", keyset: score,
+			cursor: &Cursor{Sort: "score", Desc: true, Value: strPtr("10"), ID: 5},
+			want:   "((score < '10' OR (score = '10' AND id < 5)) OR score IS NULL)",
+		},
+		{
+			name: "inside the trailing NULL block", keyset: score,
+			cursor: &Cursor{Sort: "score", Desc: true, ID: 5},
+			want:   "score IS NULL AND id < 5",
+		},
+		{name: "different sort", keyset: score, cursor: &Cursor{Sort: "name", Desc: true, ID: 5}, wantErr: true},
+		{name: "different direction", keyset: score, cursor: &Cursor{Sort: "score", ID: 5}, wantErr: true},
+		{
+			name: "NULL value for a non-nullable column", keyset: Keyset{Sort: "name", Column: "name"},
+			cursor: &Cursor{Sort: "name", ID: 5}, wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := tt.keyset.Apply(db.Model(&testRow{}), tt.cursor, 10)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var rows []testRow
+			sql := query.Find(&rows).Statement.SQL.String()
+			stmt := db.Dialector.Explain(sql, query.Statement.Vars...)
+			if !strings.Contains(stmt, "ORDER BY "+tt.keyset.OrderSQL()+" LIMIT 11") {
+				t.Errorf("missing order or look-ahead row: %s", stmt)
+			}
+			if tt.want == "" && strings.Contains(stmt, "WHERE") || !strings.Contains(stmt, tt.want) {
+				t.Errorf("statement = %s, want WHERE %s", stmt, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	db := dryRunDB(t)
+	ten := int64(10)
+	rows := []testRow{{ID: 1, Score: &ten}, {ID: 2, Score: &ten}, {ID: 3}}
+
+	tests := []struct {
+		name      string
+		rows      []testRow
+		keyset    Keyset
+		wantRows  int
+		wantValue *string
+		wantID    uint64
+	}{
+		{name: "last page", rows: rows, keyset: Keyset{Sort: "id"}, wantRows: 3},
+		{name: "by id", rows: rows, keyset: Keyset{Sort: "id"}, wantRows: 1, wantID: 1},
+		{name: "by value", rows: rows, keyset: Keyset{Sort: "score", Column: "score", Nullable: true}, wantRows: 2,
+			wantValue: strPtr("10"), wantID: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, next, err := Trim(db, tt.rows, tt.keyset, tt.wantRows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.wantRows {
+				t.Fatalf("got %d rows, want %d", len(got), tt.wantRows)
+			}
+			if tt.wantID == 0 {
+				if next != "" {
+					t.Errorf("next cursor = %q on the last page", next)
+				}
+				return
+			}
+			cursor, err := DecodeCursor(next)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cursor.ID != tt.wantID || (cursor.Value == nil) != (tt.wantValue == nil) ||
+				(cursor.Value != nil && *cursor.Value != *tt.wantValue) {
+				t.Errorf("cursor = %+v, want value %v id %d", *cursor, tt.wantValue, tt.wantID)
+			}
+		})
+	}
+
+	// A NULL sort value is carried as a nil cursor value
+	_, next, err := Trim(db, []testRow{rows[2], rows[0]}, Keyset{Sort: "score", Column: "score", Nullable: true}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor, err := DecodeCursor(next); err != nil || cursor.Value != nil || cursor.ID != 3 {
+		t.Errorf("cursor after a NULL row = %+v (%v)", cursor, err)
+	}
+}
@@ -0,0 +1,142 @@
+package pagination
+
+import (
+	"fmt"
+	"pipeline-backend/money"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Filter kinds
+const (
+	FilterEqual       = "equal"        // ?param=value
+	FilterIn          = "in"           // ?param=a,b,c
+	FilterDateRange   = "date_range"   // ?param=yyyy-mm-dd, or ?param_from=yyyy-mm-dd&param_to=yyyy-mm-dd (inclusive)
+	FilterAmountRange = "amount_range" // ?param_min=&param_max=, any amount format (1.000.000 / 1,000,000.50)
+	FilterNumberRange = "number_range" // ?param_min=&param_max=, plain numbers
+)
+
+// FilterField - One whitelisted filter of a list endpoint
+type FilterField struct {
+	Param  string
+	Column string
+	Kind   string
+}
+
+// Filter - A WHERE fragment with its arguments, usable with GORM and in raw SQL
+type Filter struct {
+	Clauses []string
+	Args    []interface{}
+}
+
+// Add - Append a condition
+func (f *Filter) Add(clause string, args ...interface{}) {
+	f.Clauses = append(f.Clauses, clause)
+	f.Args = append(f.Args, args...)
+}
+
+// SQL - The combined condition ("1=1" when no filter is set)
+func (f *Filter) SQL() string {
+	if len(f.Clauses) == 0 {
+		return "1=1"
+	}
+	return strings.Join(f.Clauses, " AND ")
+}
+
+// Apply - Add the filter to a GORM query
+func (f *Filter) Apply(query *gorm.DB) *gorm.DB {
+	if len(f.Clauses) == 0 {
+		return query
+	}
+	return query.Where(f.SQL(), f.Args...)
+}
+
+// ParseFilters - Build a filter from the query string for the given fields
+func ParseFilters(ctx *fiber.Ctx, fields []FilterField) (*Filter, error) {
+	f := &Filter{}
+
+	for _, field := range fields {
+		switch field.Kind {
+		case FilterEqual:
+			if value := ctx.Query(field.Param); value != "" {
+				f.Add(fmt.Sprintf("%s = ?", field.Column), value)
+			}
+
+		case FilterIn:
+			if values := SplitList(ctx.Query(field.Param)); len(values) > 0 {
+				f.Add(fmt.Sprintf("%s IN ?", field.Column), values)
+			}
+
+		case FilterDateRange:
+			for _, bound := range []struct{ suffix, op string }{{"", "="}, {"_from", ">="}, {"_to", "<="}} {
+				raw := ctx.Query(field.Param + bound.suffix)
+				if raw == "" {
+					continue
+				}
+				date, err := time.Parse("2006-01-02", raw)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s%s: %s (expected yyyy-mm-dd)", field.Param, bound.suffix, raw)
+				}
+				f.Add(fmt.Sprintf("%s %s ?", field.Column, bound.op), date.Format("2006-01-02"))
+			}
+
+		case FilterAmountRange, FilterNumberRange:
+			for _, bound := range []struct{ suffix, op string }{{"_min", ">="}, {"_max", "<="}} {
+				raw := ctx.Query(field.Param + bound.suffix)
+				if raw == "" {
+					continue
+				}
+				var value interface{}
+				if field.Kind == FilterAmountRange {
+					amount, err := money.Parse(raw)
+					if err != nil {
+						return nil, fmt.Errorf("invalid %s%s: %s", field.Param, bound.suffix, raw)
+					}
+					value = amount.String()
+				} else {
+					number, err := strconv.ParseFloat(raw, 64)
+					if err != nil {
+						return nil, fmt.Errorf("invalid %s%s: %s", field.Param, bound.suffix, raw)
+					}
+					value = number
+				}
+				f.Add(fmt.Sprintf("%s %s ?", field.Column, bound.op), value)
+			}
+
+		default:
+			return nil, fmt.Errorf("pagination: unknown filter kind %q", field.Kind)
+		}
+	}
+
+	return f, nil
+}
+
+// Search - LIKE '%term%' across columns, OR-ed together
+func (f *Filter) Search(term string, columns ...string) {
+	if term == "" || len(columns) == 0 {
+		return
+	}
+	like := "%" + term + "%"
+	parts := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		parts[i] = column + " LIKE ?"
+		args[i] = like
+	}
+	f.Add("("+strings.Join(parts, " OR ")+")", args...)
+}
+
+// SplitList - Split a comma separated query value, dropping blanks
+func SplitList(raw string) []string {
+	var values []string
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,220 @@
+package pagination
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Page size limits shared by every list endpoint
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 1000
+)
+
+// Sort - A whitelisted sort column
+type Sort struct {
+	Column   string // "" sorts by ID alone
+	Nullable bool   // NULLs sort last in both directions
+}
+
+// Spec - How one list endpoint filters, sorts and pages
+type Spec struct {
+	Table         string
+	Sorts         map[string]Sort
+	DefaultSort   string
+	DefaultDesc   bool
+	Filters       []FilterField
+	SearchColumns []string
+	Legacy        func(Meta) fiber.Map // pre-envelope response keys, merged in compatibility mode
+}
+
+// Params - A parsed list request
+type Params struct {
+	Page       int
+	PageSize   int
+	Cursor     string
+	CursorMode bool
+	Count      string
+	Keyset     Keyset
+	Filter     *Filter
+	Compat     bool
+	spec       Spec
+}
+
+// Meta - Paging information of the standard envelope
+type Meta struct {
+	Page            int    `json:"page,omitempty"` // offset mode only
+	PageSize        int    `json:"page_size"`
+	Total           *int64 `json:"total"` // null when not counted
+	TotalPages      *int   `json:"total_pages,omitempty"`
+	TotalIsEstimate bool   `json:"total_is_estimate"`
+	Sort            string `json:"sort"`
+	Order           string `json:"order"`
+	NextCursor      string `json:"next_cursor,omitempty"`
+	HasMore         bool   `json:"has_more"`
+}
+
+// Result - One page of rows and its meta
+type Result[T any] struct {
+	Data []T  `json:"data"`
+	Meta Meta `json:"meta"`
+}
+
+// Parse - Read the common list parameters:
+//   - page, page_size (limit is accepted as an alias), capped at MaxPageSize
+//   - cursor / pagination=cursor for keyset paging, count=exact|estimate|none
+//   - sort=<name>&order=asc|desc, or sort=-<name> for descending
+//   - search plus the spec's filters
+//   - compat=true|false to include the pre-envelope keys (default from API_LIST_COMPAT, on)
+func Parse(ctx *fiber.Ctx, spec Spec) (*Params, error) {
+	p := &Params{spec: spec, Page: 1, PageSize: DefaultPageSize}
+
+	if page, err := strconv.Atoi(ctx.Query("page")); err == nil && page > 0 {
+		p.Page = page
+	}
+	rawSize := ctx.Query("page_size", ctx.Query("limit"))
+	if size, err := strconv.Atoi(rawSize); err == nil {
+		p.PageSize = ClampLimit(size)
+	}
+
+	p.Cursor = ctx.Query("cursor")
+	p.CursorMode = p.Cursor != "" || ctx.Query("pagination") == "cursor"
+
+	defaultCount := CountExact
+	if p.CursorMode {
+		defaultCount = CountNone
+	}
+	var err error
+	if p.Count, err = ParseCountMode(ctx.Query("count"), defaultCount); err != nil {
+		return nil, err
+	}
+
+	if p.Keyset, err = parseSort(ctx, spec); err != nil {
+		return nil, err
+	}
+
+	if p.Filter, err = ParseFilters(ctx, spec.Filters); err != nil {
+		return nil, err
+	}
+	p.Filter.Search(ctx.Query("search"), spec.SearchColumns...)
+
+	p.Compat = os.Getenv("API_LIST_COMPAT") != "false"
+	if compat, err := strconv.ParseBool(ctx.Query("compat")); err == nil {
+		p.Compat = compat
+	}
+	return p, nil
+}
+
+func parseSort(ctx *fiber.Ctx, spec Spec) (Keyset, error) {
+	name := ctx.Query("sort", spec.DefaultSort)
+	desc := spec.DefaultDesc
+	if strings.HasPrefix(name, "-") {
+		name, desc = name[1:], true
+	}
+	switch strings.ToLower(ctx.Query("order")) {
+	case "":
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	default:
+		return Keyset{}, fmt.Errorf("invalid order: %s", ctx.Query("order"))
+	}
+
+	sort, ok := spec.Sorts[name]
+	if !ok {
+		return Keyset{}, fmt.Errorf("invalid sort: %s", name)
+	}
+	return Keyset{Sort: name, Column: sort.Column, Nullable: sort.Nullable, Desc: desc}, nil
+}
+
+// ClampLimit - Keep a page size within [1, MaxPageSize]
+func ClampLimit(limit int) int {
+	if limit < 1 {
+		return 1
+	}
+	if limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return limit
+}
+
+// List - Load one page for a base query (model, joins, preloads - no filters, order or limit).
+// Returns ErrInvalidCursor for a bad or mismatched cursor.
+func List[T any](db, query *gorm.DB, p *Params) (*Result[T], error) {
+	query = p.Filter.Apply(query)
+
+	meta := Meta{PageSize: p.PageSize, Sort: p.Keyset.Sort, Order: "asc"}
+	if p.Keyset.Desc {
+		meta.Order = "desc"
+	}
+
+	var err error
+	if meta.Total, meta.TotalIsEstimate, err = Total(db, query.Session(&gorm.Session{}), p.spec.Table, p.Count); err != nil {
+		return nil, err
+	}
+
+	var rows []T
+	if p.CursorMode {
+		var cursor *Cursor
+		if p.Cursor != "" {
+			if cursor, err = DecodeCursor(p.Cursor); err != nil {
+				return nil, err
+			}
+		}
+		paged, err := p.Keyset.Apply(query.Session(&gorm.Session{}), cursor, p.PageSize)
+		if err != nil {
+			return nil, err
+		}
+		if err := paged.Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		if rows, meta.NextCursor, err = Trim(db, rows, p.Keyset, p.PageSize); err != nil {
+			return nil, err
+		}
+		meta.HasMore = meta.NextCursor != ""
+	} else {
+		err := query.Session(&gorm.Session{}).
+			Order(p.Keyset.OrderSQL()).
+			Offset((p.Page - 1) * p.PageSize).
+			Limit(p.PageSize + 1).
+			Find(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		if meta.HasMore = len(rows) > p.PageSize; meta.HasMore {
+			rows = rows[:p.PageSize]
+		}
+		meta.Page = p.Page
+		if meta.Total != nil {
+			pages := int((*meta.Total + int64(p.PageSize) - 1) / int64(p.PageSize))
+			meta.TotalPages = &pages
+		}
+	}
+
+	if rows == nil {
+		rows = []T{}
+	}
+	return &Result[T]{Data: rows, Meta: meta}, nil
+}
+
+// Respond - Write the standard {data, meta} envelope, plus the endpoint's legacy keys in compatibility mode
+func Respond[T any](ctx *fiber.Ctx, p *Params, r *Result[T]) error {
+	body := fiber.Map{
+		"data": r.Data,
+		"meta": r.Meta,
+	}
+	if p.Compat && p.spec.Legacy != nil {
+		for key, value := range p.spec.Legacy(r.Meta) {
+			if _, taken := body[key]; !taken {
+				body[key] = value
+			}
+		}
+	}
+	return ctx.JSON(body)
+}
@@ -30,16 +30,70 @@ func SetupRoutes(app *fiber.App) {
 	auth.Post("/login", authController.Login)
 	auth.Post("/register", authController.Register)
 
+	// Notification opt-out link from the email footer (Public - token in the query)
+	notificationController := controllers.NewNotificationController(db)
+	api.Get("/notifications/unsubscribe", notificationController.UnsubscribeForm)
+	api.Post("/notifications/unsubscribe", notificationController.Unsubscribe)
+
 	// Protected routes - require JWT token
 	protected := api.Group("/", middleware.JWTMiddleware())
 	protected.Get("/profile", authController.GetProfile)
 	protected.Post("/change-password", authController.ChangePassword)
+	protected.Put("/users/:id/data-scope", middleware.AdminOnly(), authController.SetDataScope)
 
 	// Initialize controllers
 	di319Controller := controllers.NewDI319ImportController(db)
 
 	// Dashboard stats (protected) - Now uses DI319 data
 	protected.Get("/stats", di319Controller.GetStats)
+	protected.Get("/stats/export", di319Controller.ExportStats)
+
+	// Ad-hoc DI319 pivots (Protected)
+	analyticsController := controllers.NewAnalyticsController(db)
+	protected.Get("/analytics/di319", analyticsController.DI319)
+
+	// Leaderboards and RMFT scorecards (Protected)
+	leaderboardController := controllers.NewLeaderboardController(db)
+	protected.Get("/leaderboards", leaderboardController.GetLeaderboards)
+	protected.Get("/leaderboards/rmft/:pn", leaderboardController.GetRMFTScorecard)
+
+	// PDF branch reports (Protected) - generated in the background, kept for download
+	reportController := controllers.NewReportController(db)
+	reports := protected.Group("/reports")
+	reports.Get("/", reportController.GetAll)
+	reports.Post("/", reportController.Generate)
+	reports.Get("/:id", reportController.GetByID)
+	reports.Get("/:id/download", reportController.Download)
+	reports.Delete("/:id", middleware.AdminOnly(), reportController.Delete)
+
+	// Scheduled jobs (Admin only) - report, export, recovery and retention runs on a cron timetable
+	scheduledJobController := controllers.NewScheduledJobController(db)
+	scheduledJobs := protected.Group("/scheduled-jobs", middleware.AdminOnly())
+	scheduledJobs.Get("/", scheduledJobController.GetAll)
+	scheduledJobs.Post("/", scheduledJobController.Create)
+	scheduledJobs.Get("/runs/:runId/download", scheduledJobController.DownloadRun)
+	scheduledJobs.Get("/:id", scheduledJobController.GetByID)
+	scheduledJobs.Put("/:id", scheduledJobController.Update)
+	scheduledJobs.Delete("/:id", scheduledJobController.Delete)
+	scheduledJobs.Post("/:id/run", scheduledJobController.Run)
+	scheduledJobs.Get("/:id/runs", scheduledJobController.GetRuns)
+
+	// Email notifications (Admin only) - new candidate digests to RMFT officers, recipients and delivery log
+	notifications := protected.Group("/notifications", middleware.AdminOnly())
+	notifications.Get("/", notificationController.GetAll)
+	notifications.Post("/di319/send", notificationController.SendNewCandidates)
+	notifications.Post("/test", notificationController.SendTest)
+	notifications.Get("/recipients", notificationController.GetRecipients)
+	notifications.Put("/recipients/:pn", notificationController.SetRecipient)
+	notifications.Delete("/recipients/:pn", notificationController.DeleteRecipient)
+
+	// Global search across customers, RMFT officers and ukers (Protected)
+	searchController := controllers.NewSearchController(db)
+	protected.Get("/search", searchController.Search)
+
+	// Customer 360 (Protected)
+	customerController := controllers.NewCustomerController(db)
+	protected.Get("/customers/:cif", customerController.GetByCIF)
 
 	// RFMT routes (Protected)
 	rfmtController := controllers.NewRFMTController(db)
@@ -77,10 +131,31 @@ func SetupRoutes(app *fiber.App) {
 	productTypes.Put("/:id", productTypeController.Update)
 	productTypes.Delete("/:id", productTypeController.Delete)
 
+	// Resumable upload routes (Protected) - completed uploads are imported via "upload_id"
+	uploadController := controllers.NewUploadController(db)
+	uploads := protected.Group("/uploads")
+	uploads.Post("/", uploadController.Create)
+	uploads.Head("/:id", uploadController.Head)
+	uploads.Patch("/:id", uploadController.Patch)
+	uploads.Get("/:id", uploadController.Get)
+	uploads.Delete("/:id", uploadController.Delete)
+
+	// DI319 import profile routes (Protected - changes are admin only)
+	importProfileController := controllers.NewImportProfileController(db)
+	importProfiles := protected.Group("/import-profiles")
+	importProfiles.Get("/", importProfileController.GetAll)
+	importProfiles.Get("/:id", importProfileController.GetByID)
+	importProfiles.Post("/", middleware.AdminOnly(), importProfileController.Create)
+	importProfiles.Put("/:id", middleware.AdminOnly(), importProfileController.Update)
+	importProfiles.Delete("/:id", middleware.AdminOnly(), importProfileController.Delete)
+
 	// DI319 Import routes (Protected - same as pipeline import)
 	di319 := protected.Group("/di319")
 	di319.Get("/", di319Controller.GetAll)
+	di319.Get("/export", di319Controller.Export)
 	di319.Post("/import", di319Controller.ImportCSV)
 	di319.Get("/import/progress", di319Controller.GetImportProgress)
+	di319.Get("/compare", di319Controller.GetCompare)
+	di319.Get("/accounts/:norek/history", di319Controller.GetAccountHistory)
 	di319.Delete("/all", di319Controller.DeleteAll)
 }
@@ -53,9 +53,9 @@ const Dashboard = () => {
         <div className="bg-white rounded-lg shadow-md p-6 border-l-4 border-blue-500">
           <div className="flex items-center justify-between">
             <div>
-              <p className="text-gray-500 text-sm font-medium">Total Pipelines</p>
+              <p className="text-gray-500 text-sm font-medium">Total Accounts</p>
               <h3 className="text-3xl font-bold text-gray-800 mt-2">
-                {formatNumber(stats?.total_pipelines || 0)}
+                {formatNumber(stats?.totals?.accounts || 0)}
               </h3>
             </div>
             <div className="bg-blue-100 p-3 rounded-full">
@@ -67,9 +67,9 @@ const Dashboard = () => {
         <div className="bg-white rounded-lg shadow-md p-6 border-l-4 border-green-500">
           <div className="flex items-center justify-between">
             <div>
-              <p className="text-gray-500 text-sm font-medium">Total Proyeksi</p>
+              <p className="text-gray-500 text-sm font-medium">Total Balance</p>
               <h3 className="text-2xl font-bold text-gray-800 mt-2">
-                {formatCurrency(stats?.total_proyeksi || 0)}
+                {formatCurrency(stats?.totals?.total_balance || 0)}
               </h3>
             </div>
             <div className="bg-green-100 p-3 rounded-full">
@@ -81,9 +81,9 @@ const Dashboard = () => {
         <div className="bg-white rounded-lg shadow-md p-6 border-l-4 border-purple-500">
           <div className="flex items-center justify-between">
             <div>
-              <p className="text-gray-500 text-sm font-medium">Strategies</p>
+              <p className="text-gray-500 text-sm font-medium">Drop Candidates</p>
               <h3 className="text-3xl font-bold text-gray-800 mt-2">
-                {stats?.strategy_stats?.length || 0}
+                {formatNumber(stats?.totals?.drop_count || 0)}
               </h3>
             </div>
             <div className="bg-purple-100 p-3 rounded-full">
@@ -95,40 +95,40 @@ const Dashboard = () => {
 
       {/* Charts */}
       <div className="grid grid-cols-1 lg:grid-cols-2 gap-6">
-        {/* Strategy Chart */}
+        {/* Branch Chart */}
         <div className="bg-white rounded-lg shadow-md p-6">
-          <h2 className="text-xl font-bold text-gray-800 mb-4">Proyeksi per Strategy</h2>
+          <h2 className="text-xl font-bold text-gray-800 mb-4">Balance per Branch</h2>
           <ResponsiveContainer width="100%" height={300}>
-            <BarChart data={stats?.strategy_stats || []}>
+            <BarChart data={stats?.dimensions?.branch || []}>
               <CartesianGrid strokeDasharray="3 3" />
-              <XAxis dataKey="strategy" />
+              <XAxis dataKey="name" />
               <YAxis />
               <Tooltip 
                 formatter={(value) => formatCurrency(value)}
               />
               <Legend />
-              <Bar dataKey="total_proyeksi" fill="#3b82f6" name="Total Proyeksi" />
+              <Bar dataKey="total_balance" fill="#3b82f6" name="Total Balance" />
             </BarChart>
           </ResponsiveContainer>
         </div>
 
-        {/* Segment Chart */}
+        {/* Product Type Chart */}
         <div className="bg-white rounded-lg shadow-md p-6">
-          <h2 className="text-xl font-bold text-gray-800 mb-4">Distribusi per Segment</h2>
+          <h2 className="text-xl font-bold text-gray-800 mb-4">Distribusi per Product Type</h2>
           <ResponsiveContainer width="100%" height={300}>
             <PieChart>
               <Pie
-                data={stats?.segment_stats || []}
+                data={stats?.dimensions?.type || []}
                 cx="50%"
                 cy="50%"
                 labelLine={false}
-                label={({ segment, percent }) => `${segment} (${(percent * 100).toFixed(0)}%)`}
+                label={({ name, percent }) => `${name} (${(percent * 100).toFixed(0)}%)`}
                 outerRadius={100}
                 fill="#8884d8"
-                dataKey="count"
-                nameKey="segment"
+                dataKey="accounts"
+                nameKey="name"
               >
-                {(stats?.segment_stats || []).map((entry, index) => (
+                {(stats?.dimensions?.type || []).map((entry, index) => (
                   <Cell key={`cell-${index}`} fill={COLORS[index % COLORS.length]} />
                 ))}
               </Pie>
@@ -142,24 +142,24 @@ const Dashboard = () => {
       <div className="mt-6 bg-white rounded-lg shadow-md p-6">
         <h2 className="text-xl font-bold text-gray-800 mb-4">Detail Statistics</h2>
         <div className="grid grid-cols-1 md:grid-cols-2 gap-6">
-          {/* Strategy Stats */}
+          {/* Branch Stats */}
           <div>
-            <h3 className="text-lg font-semibold text-gray-700 mb-3">Strategy Breakdown</h3>
+            <h3 className="text-lg font-semibold text-gray-700 mb-3">Branch Breakdown</h3>
             <div className="overflow-x-auto">
               <table className="min-w-full divide-y divide-gray-200">
                 <thead className="bg-gray-50">
                   <tr>
-                    <th className="px-4 py-3 text-left text-xs font-medium text-gray-500 uppercase">Strategy</th>
-                    <th className="px-4 py-3 text-left text-xs font-medium text-gray-500 uppercase">Count</th>
+                    <th className="px-4 py-3 text-left text-xs font-medium text-gray-500 uppercase">Branch</th>
+                    <th className="px-4 py-3 text-left text-xs font-medium text-gray-500 uppercase">Accounts</th>
                     <th className="px-4 py-3 text-left text-xs font-medium text-gray-500 uppercase">Total</th>
                   </tr>
                 </thead>
                 <tbody className="bg-white divide-y divide-gray-200">
-                  {stats?.strategy_stats?.map((item, index) => (
+                  {stats?.dimensions?.branch?.map((item, index) => (
                     <tr key={index}>
-                      <td className="px-4 py-3 whitespace-nowrap text-sm font-medium text-gray-900">{item.strategy}</td>
-                      <td className="px-4 py-3 whitespace-nowrap text-sm text-gray-500">{formatNumber(item.count)}</td>
-                      <td className="px-4 py-3 whitespace-nowrap text-sm text-gray-500">{formatCurrency(item.total_proyeksi)}</td>
+                      <td className="px-4 py-3 whitespace-nowrap text-sm font-medium text-gray-900">{item.label || item.name}</td>
+                      <td className="px-4 py-3 whitespace-nowrap text-sm text-gray-500">{formatNumber(item.accounts)}</td>
+                      <td className="px-4 py-3 whitespace-nowrap text-sm text-gray-500">{formatCurrency(item.total_balance)}</td>
                     </tr>
                   ))}
                 </tbody>
@@ -167,24 +167,24 @@ const Dashboard = () => {
             </div>
           </div>
 
-          {/* Segment Stats */}
+          {/* Product Type Stats */}
           <div>
-            <h3 className="text-lg font-semibold text-gray-700 mb-3">Segment Breakdown</h3>
+            <h3 className="text-lg font-semibold text-gray-700 mb-3">Product Type Breakdown</h3>
             <div className="overflow-x-auto">
               <table className="min-w-full divide-y divide-gray-200">
                 <thead className="bg-gray-50">
                   <tr>
-                    <th className="px-4 py-3 text-left text-xs font-medium text-gray-500 uppercase">Segment</th>
-                    <th className="px-4 py-3 text-left text-xs font-medium text-gray-500 uppercase">Count</th>
+                    <th className="px-4 py-3 text-left text-xs font-medium text-gray-500 uppercase">Type</th>
+                    <th className="px-4 py-3 text-left text-xs font-medium text-gray-500 uppercase">Accounts</th>
                     <th className="px-4 py-3 text-left text-xs font-medium text-gray-500 uppercase">Total</th>
                   </tr>
                 </thead>
                 <tbody className="bg-white divide-y divide-gray-200">
-                  {stats?.segment_stats?.map((item, index) => (
+                  {stats?.dimensions?.type?.map((item, index) => (
                     <tr key={index}>
-                      <td className="px-4 py-3 whitespace-nowrap text-sm font-medium text-gray-900">{item.segment}</td>
-                      <td className="px-4 py-3 whitespace-nowrap text-sm text-gray-500">{formatNumber(item.count)}</td>
-                      <td className="px-4 py-3 whitespace-nowrap text-sm text-gray-500">{formatCurrency(item.total_proyeksi)}</td>
+                      <td className="px-4 py-3 whitespace-nowrap text-sm font-medium text-gray-900">{item.label || item.name}</td>
+                      <td className="px-4 py-3 whitespace-nowrap text-sm text-gray-500">{formatNumber(item.accounts)}</td>
+                      <td className="px-4 py-3 whitespace-nowrap text-sm text-gray-500">{formatCurrency(item.total_balance)}</td>
                     </tr>
                   ))}
                 </tbody>